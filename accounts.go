@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+// Account states, from least to most severe. Limited accounts can still sign
+// in and post, but only they see their chirps; suspended ones are locked out
+// until suspended_until and banned ones for good.
+const (
+	accountActive    = "active"
+	accountLimited   = "limited"
+	accountSuspended = "suspended"
+	accountBanned    = "banned"
+)
+
+var accountStates = []string{accountActive, accountLimited, accountSuspended, accountBanned}
+
+// the audit trail entry for each state a moderator can put an account in
+var accountActions = map[string]string{
+	accountActive:    "restore_user",
+	accountLimited:   resolutionLimitUser,
+	accountSuspended: resolutionSuspendUser,
+	accountBanned:    resolutionBanUser,
+}
+
+type Account struct {
+	ID             uuid.UUID  `json:"id"`
+	Handle         string     `json:"handle"`
+	State          string     `json:"state"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
+	IsModerator    bool       `json:"is_moderator"`
+}
+
+// accountState is the state in effect now; a suspension that has run out
+// counts as active.
+func accountState(dbUser database.User) string {
+	if dbUser.AccountState == accountSuspended && !(dbUser.SuspendedUntil.Valid && dbUser.SuspendedUntil.Time.After(time.Now().UTC())) {
+		return accountActive
+	}
+	return dbUser.AccountState
+}
+
+// lockedOut explains why a user may not sign in or post, or returns "" if
+// they may.
+func lockedOut(dbUser database.User) string {
+	switch accountState(dbUser) {
+	case accountSuspended:
+		return "Your account is suspended until " + dbUser.SuspendedUntil.Time.Format(time.RFC3339)
+	case accountBanned:
+		return "Your account has been banned"
+	}
+	return ""
+}
+
+func account(dbUser database.User) Account {
+	acc := Account{ID: dbUser.ID, Handle: dbUser.Handle.String, State: accountState(dbUser), IsModerator: dbUser.IsModerator}
+	if acc.State == accountSuspended {
+		acc.SuspendedUntil = &dbUser.SuspendedUntil.Time
+	}
+	return acc
+}
+
+// setAccountState moves a user to a new state. Suspended and banned users are
+// signed out everywhere; limited ones are deliberately left alone so they
+// can't tell.
+func setAccountState(ctx context.Context, q *database.Queries, userID uuid.UUID, state string, until time.Time) (database.User, error) {
+	suspendedUntil := sql.NullTime{}
+	if state == accountSuspended {
+		suspendedUntil = sql.NullTime{Time: until, Valid: true}
+	}
+	dbUser, err := q.SetAccountState(ctx, database.SetAccountStateParams{ID: userID, AccountState: state, SuspendedUntil: suspendedUntil})
+	if err != nil {
+		return dbUser, err
+	}
+	if state != accountSuspended && state != accountBanned {
+		return dbUser, nil
+	}
+	err = q.RevokeUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return dbUser, err
+	}
+	return dbUser, publishEvent(ctx, q, database.CreateStreamEventParams{Type: streamEventSessionRevoked, Topics: []string{accountTopic(userID)}})
+}
+
+func (cfg *apiConfig) getAccountHandler(w http.ResponseWriter, r *http.Request) {
+	_, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, account(dbUser))
+}
+
+// updateAccountHandler lets a moderator limit, suspend, ban or restore an
+// account outside of a report. suspend_hours only applies to suspensions.
+func (cfg *apiConfig) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+	type parameters struct {
+		State        string `json:"state"`
+		SuspendHours int    `json:"suspend_hours"`
+		Note         string `json:"note"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	action, ok := accountActions[params.State]
+	if !ok {
+		respondWithText(w, 400, fmt.Sprintf("state must be one of %s", strings.Join(accountStates, ", ")))
+		return
+	}
+	suspension, err := suspensionLength(params.SuspendHours)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbUser.ID == moderatorID {
+		respondWithText(w, 400, "You cannot change your own account state")
+		return
+	}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		dbUser, err = setAccountState(r.Context(), q, dbUser.ID, params.State, time.Now().UTC().Add(suspension))
+		if err != nil {
+			return err
+		}
+		return q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID:  uuid.NullUUID{UUID: moderatorID, Valid: true},
+			Action:       action,
+			TargetUserID: uuid.NullUUID{UUID: dbUser.ID, Valid: true},
+			Note:         strings.TrimSpace(params.Note),
+		})
+	})
+	if err != nil {
+		fmt.Printf("Error updating account state:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, account(dbUser))
+}
+
+// suspensionLength turns a requested number of hours into a duration, with 0
+// meaning the default.
+func suspensionLength(hours int) (time.Duration, error) {
+	if hours == 0 {
+		return defaultSuspension, nil
+	}
+	// checked before converting, as a large count would overflow the duration
+	if hours < 0 || float64(hours) > maxSuspension.Hours() {
+		return 0, fmt.Errorf("suspend_hours must be between 1 and %d", int(maxSuspension.Hours()))
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
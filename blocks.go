@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/stream"
+)
+
+// Blocks and mutes are enforced by the queries themselves (can_view_chirp,
+// FollowUser, AddChirpMentions, NotificationEnabled and the timelines), so
+// the handlers here only record them.
+
+func (cfg *apiConfig) blockHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.updateRelation(w, r, "You cannot block yourself", func(ctx context.Context, q *database.Queries, userID, otherID uuid.UUID) error {
+		n, err := q.BlockUser(ctx, database.BlockUserParams{BlockerID: userID, BlockedID: otherID})
+		if err != nil || n == 0 {
+			return err
+		}
+		// a block ends any follow between the two users, in both directions
+		for _, pair := range [][2]uuid.UUID{{userID, otherID}, {otherID, userID}} {
+			n, err := q.UnfollowUser(ctx, database.UnfollowUserParams{FollowerID: pair[0], FolloweeID: pair[1]})
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				err = cfg.timeline.unfollowed(ctx, q, pair[0], pair[1])
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (cfg *apiConfig) unblockHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.updateRelation(w, r, "You cannot unblock yourself", func(ctx context.Context, q *database.Queries, userID, otherID uuid.UUID) error {
+		_, err := q.UnblockUser(ctx, database.UnblockUserParams{BlockerID: userID, BlockedID: otherID})
+		return err
+	})
+}
+
+// muted users are never told; their chirps just stop showing up in the
+// muter's timelines and notifications
+func (cfg *apiConfig) muteHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.updateRelation(w, r, "You cannot mute yourself", func(ctx context.Context, q *database.Queries, userID, otherID uuid.UUID) error {
+		_, err := q.MuteUser(ctx, database.MuteUserParams{MuterID: userID, MutedID: otherID})
+		return err
+	})
+}
+
+func (cfg *apiConfig) unmuteHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.updateRelation(w, r, "You cannot unmute yourself", func(ctx context.Context, q *database.Queries, userID, otherID uuid.UUID) error {
+		_, err := q.UnmuteUser(ctx, database.UnmuteUserParams{MuterID: userID, MutedID: otherID})
+		return err
+	})
+}
+
+func (cfg *apiConfig) updateRelation(w http.ResponseWriter, r *http.Request, selfMsg string, update func(context.Context, *database.Queries, uuid.UUID, uuid.UUID) error) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	other, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if other.ID == userID {
+		respondWithText(w, 400, selfMsg)
+		return
+	}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		return update(r.Context(), q, userID, other.ID)
+	})
+	if err != nil {
+		fmt.Printf("Error updating block or mute: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) blocksHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	rows, err := cfg.dbQueries.GetBlockedUsers(r.Context(), database.GetBlockedUsersParams{UserID: userID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	list := UserList{Users: make([]UserSummary, 0, len(rows))}
+	for _, row := range rows {
+		list.Users = append(list.Users, *userSummary(row.ID, row.Handle, row.DisplayName, row.AvatarKey))
+	}
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		list.NextCursor = nextCursor(len(rows), limit, pageCursor{Time: last.BlockedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, list)
+}
+
+func (cfg *apiConfig) mutesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	rows, err := cfg.dbQueries.GetMutedUsers(r.Context(), database.GetMutedUsersParams{UserID: userID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	list := UserList{Users: make([]UserSummary, 0, len(rows))}
+	for _, row := range rows {
+		list.Users = append(list.Users, *userSummary(row.ID, row.Handle, row.DisplayName, row.AvatarKey))
+	}
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		list.NextCursor = nextCursor(len(rows), limit, pageCursor{Time: last.MutedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, list)
+}
+
+// blockedTopics lists the user topics of everyone on either side of a block
+// with userID. Stream events are rendered once for every subscriber, so
+// chirps published to these topics are dropped per connection instead.
+func (cfg *apiConfig) blockedTopics(ctx context.Context, userID uuid.UUID) (map[string]bool, error) {
+	hidden := map[string]bool{}
+	if userID == uuid.Nil {
+		return hidden, nil
+	}
+	ids, err := cfg.dbQueries.GetBlockedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		hidden[userTopic(id)] = true
+	}
+	return hidden, nil
+}
+
+func hiddenEvent(event stream.Event, hidden map[string]bool) bool {
+	for _, topic := range event.Topics {
+		if hidden[topic] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/spam"
+)
+
+const maxChirpLength = 140
+
+type chirpInput struct {
+	Body      string
+	InReplyTo uuid.NullUUID
+	RechirpOf uuid.NullUUID
+	QuoteOf   uuid.NullUUID
+	MediaIDs  []uuid.UUID
+	Poll      *pollInput
+	// public, followers or mentioned; empty means public
+	Visibility string
+	// zero publishes the chirp immediately
+	PublishAt time.Time
+	// the draft being published, deleted along with creating the chirp
+	Draft *database.Draft
+	// the solved challenge, when a previous attempt asked for one
+	Verification *verificationInput
+}
+
+// requestError is returned for problems with the client's input, carrying
+// the status code and message the handler should respond with.
+type requestError struct {
+	status int
+	msg    string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+func validateChirpBody(body string) error {
+	if len(body) > maxChirpLength {
+		return &requestError{400, fmt.Sprintf("Chirp length must be no greater than %d characters", maxChirpLength)}
+	}
+	return nil
+}
+
+func conversationID(dbChirp database.Chirp) uuid.UUID {
+	if dbChirp.ConversationID.Valid {
+		return dbChirp.ConversationID.UUID
+	}
+	return dbChirp.ID
+}
+
+// loadTarget fetches a chirp that a new chirp by userID refers to. Plain
+// rechirps have no content of their own, so references to them are redirected
+// to the original.
+func loadTarget(ctx context.Context, q *database.Queries, id, userID uuid.UUID, missing string) (database.Chirp, error) {
+	target, err := q.GetChirp(ctx, id)
+	if err == nil && target.RechirpOfID.Valid {
+		target, err = q.GetChirp(ctx, target.RechirpOfID.UUID)
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return database.Chirp{}, &requestError{404, missing}
+		}
+		return database.Chirp{}, err
+	}
+	visible, err := canView(ctx, q, target, userID)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	if target.DeletedAt.Valid || target.PublishAt.Valid || !visible {
+		return database.Chirp{}, &requestError{404, missing}
+	}
+	return target, nil
+}
+
+func (cfg *apiConfig) createChirp(ctx context.Context, userID uuid.UUID, input chirpInput) (database.Chirp, error) {
+	if input.RechirpOf.Valid && (input.Body != "" || input.InReplyTo.Valid || input.QuoteOf.Valid || len(input.MediaIDs) > 0 || input.Poll != nil) {
+		return database.Chirp{}, &requestError{400, "A rechirp cannot have a body, reply, quote, media or poll"}
+	}
+	if input.RechirpOf.Valid && !input.PublishAt.IsZero() {
+		return database.Chirp{}, &requestError{400, "A rechirp cannot be scheduled"}
+	}
+	if len(input.MediaIDs) > maxMediaPerChirp {
+		return database.Chirp{}, &requestError{400, fmt.Sprintf("A chirp can have at most %d media attachments", maxMediaPerChirp)}
+	}
+	if input.QuoteOf.Valid && strings.TrimSpace(input.Body) == "" {
+		return database.Chirp{}, &requestError{400, "A quote chirp must have a body"}
+	}
+	err := validateChirpBody(input.Body)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	visibility, err := validateVisibility(input.Visibility)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	var pollDuration time.Duration
+	if input.Poll != nil {
+		if len(input.MediaIDs) > 0 {
+			return database.Chirp{}, &requestError{400, "A chirp cannot have both media and a poll"}
+		}
+		pollDuration, err = validatePoll(input.Poll)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	if !input.PublishAt.IsZero() {
+		err = validatePublishAt(input.PublishAt)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+	}
+	verdict, err := cfg.screenChirp(ctx, userID, uuid.Nil, input.Body, input.Verification)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	var dbChirp database.Chirp
+	hasLinks := false
+	err = cfg.inTx(ctx, func(q *database.Queries) error {
+		dbUser, err := q.GetUserByID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if msg := lockedOut(dbUser); msg != "" {
+			return &requestError{403, msg}
+		}
+		params := database.CreateChirpParams{Body: input.Body, UserID: userID, Visibility: visibility}
+		if input.InReplyTo.Valid {
+			parent, err := loadTarget(ctx, q, input.InReplyTo.UUID, userID, "The chirp being replied to does not exist")
+			if err != nil {
+				return err
+			}
+			params.InReplyToID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+			params.ConversationID = uuid.NullUUID{UUID: conversationID(parent), Valid: true}
+		}
+		if input.RechirpOf.Valid {
+			original, err := loadTarget(ctx, q, input.RechirpOf.UUID, userID, "The chirp being rechirped does not exist")
+			if err != nil {
+				return err
+			}
+			// a rechirp would show the original to the rechirper's own audience
+			if original.Visibility != visibilityPublic {
+				return &requestError{400, "Only public chirps can be rechirped"}
+			}
+			params.RechirpOfID = uuid.NullUUID{UUID: original.ID, Valid: true}
+		}
+		if input.QuoteOf.Valid {
+			quoted, err := loadTarget(ctx, q, input.QuoteOf.UUID, userID, "The chirp being quoted does not exist")
+			if err != nil {
+				return err
+			}
+			params.QuoteOfID = uuid.NullUUID{UUID: quoted.ID, Valid: true}
+		}
+		if !input.PublishAt.IsZero() {
+			scheduled, err := q.CountScheduledChirps(ctx, userID)
+			if err != nil {
+				return err
+			}
+			if scheduled >= maxScheduledChirps {
+				return &requestError{400, fmt.Sprintf("You can have at most %d scheduled chirps", maxScheduledChirps)}
+			}
+			params.PublishAt = sql.NullTime{Time: input.PublishAt.UTC(), Valid: true}
+		}
+		if input.Draft != nil {
+			// only the version that was read is deleted, so a save or another
+			// publish racing this one makes it fail rather than be lost
+			n, err := q.DeleteDraft(ctx, database.DeleteDraftParams{ID: input.Draft.ID, UserID: userID, LastUpdatedAt: sql.NullTime{Time: input.Draft.UpdatedAt, Valid: true}})
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				return &requestError{409, "The draft was changed or published elsewhere"}
+			}
+		}
+		dbChirp, err = q.CreateChirp(ctx, params)
+		if err != nil {
+			if strings.Contains(err.Error(), "duplicate key") {
+				return &requestError{409, "You have already rechirped this chirp"}
+			}
+			return err
+		}
+		if len(input.MediaIDs) > 0 {
+			n, err := q.AttachMedia(ctx, database.AttachMediaParams{ChirpID: dbChirp.ID, Ids: input.MediaIDs, UserID: userID})
+			if err != nil {
+				return err
+			}
+			// duplicated IDs also land here, as each row is only updated once
+			if n != int64(len(input.MediaIDs)) {
+				return &requestError{400, "Media not found or already attached to a chirp"}
+			}
+		}
+		if input.Poll != nil {
+			err = q.CreatePoll(ctx, database.CreatePollParams{ChirpID: dbChirp.ID, DurationSeconds: int32(pollDuration.Seconds())})
+			if err != nil {
+				return err
+			}
+			err = q.AddPollOptions(ctx, database.AddPollOptionsParams{ChirpID: dbChirp.ID, Options: input.Poll.Options})
+			if err != nil {
+				return err
+			}
+		}
+		err = recordSpamCheck(ctx, q, userID, uuid.NullUUID{UUID: dbChirp.ID, Valid: true}, dbChirp.Body, verdict, false)
+		if err != nil {
+			return err
+		}
+		// held chirps stay unpublished, and visible only to their author,
+		// until a moderator approves them
+		if verdict.Action == spam.Hold {
+			_, err = q.HideChirp(ctx, dbChirp.ID)
+			if err != nil {
+				return err
+			}
+			dbChirp.HiddenAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+			return nil
+		}
+		if dbChirp.PublishAt.Valid {
+			return nil
+		}
+		hasLinks, err = cfg.publishChirp(ctx, q, dbChirp)
+		return err
+	})
+	if err == nil && hasLinks {
+		cfg.wakeUnfurler()
+	}
+	return dbChirp, err
+}
+
+// publishChirp does everything that makes a chirp visible to other users:
+// counters, hashtags, links, mentions, notifications, timelines and the live
+// stream. It runs when a chirp is posted, or when the scheduler publishes a
+// scheduled one, and reports whether the chirp has links to unfurl.
+func (cfg *apiConfig) publishChirp(ctx context.Context, q *database.Queries, dbChirp database.Chirp) (bool, error) {
+	// user ID -> notification type; each user hears about a chirp once
+	notifications := map[uuid.UUID]string{}
+	if dbChirp.InReplyToID.Valid {
+		err := q.IncrementReplyCount(ctx, dbChirp.InReplyToID.UUID)
+		if err != nil {
+			return false, err
+		}
+		parent, err := q.GetChirp(ctx, dbChirp.InReplyToID.UUID)
+		if err != nil {
+			return false, err
+		}
+		notifications[parent.UserID] = notificationReply
+	}
+	if dbChirp.RechirpOfID.Valid {
+		err := q.IncrementRechirpCount(ctx, dbChirp.RechirpOfID.UUID)
+		if err != nil {
+			return false, err
+		}
+		original, err := q.GetChirp(ctx, dbChirp.RechirpOfID.UUID)
+		if err != nil {
+			return false, err
+		}
+		notifications[original.UserID] = notificationRechirp
+	}
+	if dbChirp.QuoteOfID.Valid {
+		quoted, err := q.GetChirp(ctx, dbChirp.QuoteOfID.UUID)
+		if err != nil {
+			return false, err
+		}
+		if _, ok := notifications[quoted.UserID]; !ok {
+			notifications[quoted.UserID] = notificationQuote
+		}
+	}
+	// polls start running when their chirp is published
+	err := q.OpenPoll(ctx, dbChirp.ID)
+	if err != nil {
+		return false, err
+	}
+	err = tagChirp(ctx, q, dbChirp)
+	if err != nil {
+		return false, err
+	}
+	hasLinks, err := linkChirp(ctx, q, dbChirp)
+	if err != nil {
+		return false, err
+	}
+	mentioned, err := mentionChirp(ctx, q, dbChirp)
+	if err != nil {
+		return false, err
+	}
+	for _, mentionedID := range mentioned {
+		if _, ok := notifications[mentionedID]; !ok {
+			notifications[mentionedID] = notificationMention
+		}
+	}
+	for recipient, kind := range notifications {
+		// a rechirp notification is about the original, everything else points at the new chirp
+		chirpID := dbChirp.ID
+		if kind == notificationRechirp {
+			chirpID = dbChirp.RechirpOfID.UUID
+		} else {
+			// e.g. a followers-only reply to someone who doesn't follow the author
+			visible, err := canView(ctx, q, dbChirp, recipient)
+			if err != nil {
+				return false, err
+			}
+			if !visible {
+				continue
+			}
+		}
+		err = cfg.notify(ctx, q, recipient, dbChirp.UserID, kind, chirpID)
+		if err != nil {
+			return false, err
+		}
+	}
+	err = cfg.timeline.chirpCreated(ctx, q, dbChirp)
+	if err != nil {
+		return false, err
+	}
+	return hasLinks, publishChirpEvent(ctx, q, streamEventCreated, dbChirp)
+}
+
+// respondWithOwnChirp responds with a chirp the caller has just written.
+func (cfg *apiConfig) respondWithOwnChirp(w http.ResponseWriter, r *http.Request, code int, userID uuid.UUID, dbChirp database.Chirp) {
+	chirp, err := cfg.renderChirp(r.Context(), userID, dbChirp)
+	if err != nil {
+		fmt.Printf("Error rendering chirp:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	chirp.Body = cleanBody(chirp.Body)
+	chirp.Entities = chirpEntities(chirp.Body, chirp.Entities.mentionedUsers())
+	respondWithJSON(w, code, chirp)
+}
+
+func (cfg *apiConfig) deleteChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	visible, err := canView(r.Context(), cfg.dbQueries, dbChirp, userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbChirp.DeletedAt.Valid || !visible {
+		w.WriteHeader(404)
+		return
+	}
+	if dbChirp.UserID != userID {
+		w.WriteHeader(403)
+		return
+	}
+	// nothing has seen a scheduled chirp yet, so there is nothing to undo
+	if dbChirp.PublishAt.Valid {
+		cfg.cancelScheduledChirp(w, r, userID, chirpID)
+		return
+	}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		return removeChirp(r.Context(), q, dbChirp)
+	})
+	if err != nil {
+		fmt.Printf("Error deleting chirp:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// renderChirps converts database rows into API responses, loading everything
+// embedded in the response (authors, ...) in batches rather than per chirp.
+// viewerID is the authenticated caller, or uuid.Nil for anonymous requests.
+func (cfg *apiConfig) renderChirps(ctx context.Context, viewerID uuid.UUID, dbChirps []database.Chirp) ([]Chirp, error) {
+	return cfg.renderChirpList(ctx, viewerID, dbChirps, true)
+}
+
+// renderChirpList does the work for renderChirps. Rechirped and quoted chirps
+// are only embedded one level deep, so embed is false when rendering those.
+func (cfg *apiConfig) renderChirpList(ctx context.Context, viewerID uuid.UUID, dbChirps []database.Chirp, embed bool) ([]Chirp, error) {
+	authorIDs := make([]uuid.UUID, 0, len(dbChirps))
+	seen := make(map[uuid.UUID]bool, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		if !seen[dbChirp.UserID] {
+			seen[dbChirp.UserID] = true
+			authorIDs = append(authorIDs, dbChirp.UserID)
+		}
+	}
+	authors, err := cfg.getUserSummaries(ctx, authorIDs)
+	if err != nil {
+		return nil, err
+	}
+	chirpIDs := make([]uuid.UUID, 0, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		chirpIDs = append(chirpIDs, dbChirp.ID)
+	}
+	mentions, err := cfg.getMentions(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	media, err := cfg.getMediaAttachments(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	previews, err := cfg.getLinkPreviews(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	polls, err := cfg.getPolls(ctx, viewerID, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	var liked map[uuid.UUID]bool
+	if viewerID != uuid.Nil {
+		likedIDs, err := cfg.dbQueries.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{UserID: viewerID, ChirpIds: chirpIDs})
+		if err != nil {
+			return nil, err
+		}
+		liked = make(map[uuid.UUID]bool, len(likedIDs))
+		for _, id := range likedIDs {
+			liked[id] = true
+		}
+	}
+	var embedded map[uuid.UUID]*Chirp
+	if embed {
+		embedded, err = cfg.renderEmbedded(ctx, viewerID, dbChirps)
+		if err != nil {
+			return nil, err
+		}
+	}
+	chirps := make([]Chirp, 0, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		chirp := Chirp{ID: dbChirp.ID, CreatedAt: dbChirp.CreatedAt.Time, UpdatedAt: dbChirp.UpdatedAt.Time, Body: dbChirp.Body, UserID: dbChirp.UserID, Author: authors[dbChirp.UserID], ConversationID: conversationID(dbChirp), ReplyCount: dbChirp.ReplyCount, LikeCount: dbChirp.LikeCount, RechirpCount: dbChirp.RechirpCount, Entities: chirpEntities(dbChirp.Body, mentions[dbChirp.ID]), Media: media[dbChirp.ID], LinkPreview: previews[dbChirp.ID], Poll: polls[dbChirp.ID], Edited: dbChirp.EditedAt.Valid, Visibility: dbChirp.Visibility}
+		if dbChirp.InReplyToID.Valid {
+			chirp.InReplyToID = &dbChirp.InReplyToID.UUID
+		}
+		if dbChirp.PublishAt.Valid {
+			chirp.PublishAt = &dbChirp.PublishAt.Time
+		}
+		if dbChirp.RechirpOfID.Valid {
+			chirp.RechirpOf = embedded[dbChirp.RechirpOfID.UUID]
+		}
+		if dbChirp.QuoteOfID.Valid {
+			chirp.QuoteOf = embedded[dbChirp.QuoteOfID.UUID]
+		}
+		if liked != nil {
+			likedByMe := liked[dbChirp.ID]
+			chirp.LikedByMe = &likedByMe
+		}
+		if dbChirp.DeletedAt.Valid {
+			chirp.Deleted = true
+			chirp.Author = nil
+			chirp.Entities = nil
+			chirp.Media = nil
+			chirp.LinkPreview = nil
+			chirp.Poll = nil
+			chirp.Edited = false
+		}
+		chirps = append(chirps, chirp)
+	}
+	return chirps, nil
+}
+
+func (cfg *apiConfig) renderEmbedded(ctx context.Context, viewerID uuid.UUID, dbChirps []database.Chirp) (map[uuid.UUID]*Chirp, error) {
+	ids := []uuid.UUID{}
+	for _, dbChirp := range dbChirps {
+		if dbChirp.RechirpOfID.Valid {
+			ids = append(ids, dbChirp.RechirpOfID.UUID)
+		}
+		if dbChirp.QuoteOfID.Valid {
+			ids = append(ids, dbChirp.QuoteOfID.UUID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	originals, err := cfg.dbQueries.GetVisibleChirpsByIDs(ctx, database.GetVisibleChirpsByIDsParams{Ids: ids, ViewerID: viewerID})
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := cfg.renderChirpList(ctx, viewerID, originals, false)
+	if err != nil {
+		return nil, err
+	}
+	embedded := make(map[uuid.UUID]*Chirp, len(rendered))
+	for i := range rendered {
+		embedded[rendered[i].ID] = &rendered[i]
+	}
+	return embedded, nil
+}
+
+func (cfg *apiConfig) renderChirp(ctx context.Context, viewerID uuid.UUID, dbChirp database.Chirp) (Chirp, error) {
+	chirps, err := cfg.renderChirps(ctx, viewerID, []database.Chirp{dbChirp})
+	if err != nil {
+		return Chirp{}, err
+	}
+	return chirps[0], nil
+}
+
+// getChirpsInOrder loads the chirps the viewer may see by ID, returning them
+// in the order of ids.
+func (cfg *apiConfig) getChirpsInOrder(ctx context.Context, viewerID uuid.UUID, ids []uuid.UUID) ([]database.Chirp, error) {
+	dbChirps, err := cfg.dbQueries.GetVisibleChirpsByIDs(ctx, database.GetVisibleChirpsByIDsParams{Ids: ids, ViewerID: viewerID})
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]database.Chirp, len(dbChirps))
+	for _, dbChirp := range dbChirps {
+		byID[dbChirp.ID] = dbChirp
+	}
+	ordered := make([]database.Chirp, 0, len(ids))
+	for _, id := range ids {
+		if dbChirp, ok := byID[id]; ok {
+			ordered = append(ordered, dbChirp)
+		}
+	}
+	return ordered, nil
+}
+
+// removeChirp tombstones a published chirp. Replies keep pointing at the
+// tombstone so threads stay intact.
+func removeChirp(ctx context.Context, q *database.Queries, dbChirp database.Chirp) error {
+	_, err := q.TombstoneChirp(ctx, dbChirp.ID)
+	if err != nil {
+		return err
+	}
+	// earlier versions would otherwise outlive the deletion
+	err = q.DeleteChirpRevisions(ctx, dbChirp.ID)
+	if err != nil {
+		return err
+	}
+	// as would its media, until the cleanup worker deletes it as unused
+	err = q.DetachMedia(ctx, dbChirp.ID)
+	if err != nil {
+		return err
+	}
+	// chirps held for spam review were never published, so never counted
+	held, err := q.ChirpHeld(ctx, uuid.NullUUID{UUID: dbChirp.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	if dbChirp.InReplyToID.Valid && !held {
+		err = q.DecrementReplyCount(ctx, dbChirp.InReplyToID.UUID)
+		if err != nil {
+			return err
+		}
+	}
+	if dbChirp.RechirpOfID.Valid && !held {
+		err = q.DecrementRechirpCount(ctx, dbChirp.RechirpOfID.UUID)
+		if err != nil {
+			return err
+		}
+	}
+	return publishChirpEvent(ctx, q, streamEventDeleted, dbChirp)
+}
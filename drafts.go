@@ -0,0 +1,262 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+const (
+	maxDrafts = 100
+	// drafts may run over the chirp limit while being worked on; publishing
+	// enforces maxChirpLength
+	maxDraftLength = 1000
+)
+
+type Draft struct {
+	ID          uuid.UUID  `json:"id"`
+	Body        string     `json:"body"`
+	InReplyToID *uuid.UUID `json:"in_reply_to_id,omitempty"`
+	QuoteOfID   *uuid.UUID `json:"quote_of_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type draftParameters struct {
+	Body      string        `json:"body"`
+	InReplyTo uuid.NullUUID `json:"in_reply_to_id"`
+	QuoteOf   uuid.NullUUID `json:"quote_of_id"`
+	// the updated_at the client last saw; a mismatch means another device saved first
+	UpdatedAt *time.Time `json:"updated_at"`
+}
+
+func draft(dbDraft database.Draft) Draft {
+	d := Draft{ID: dbDraft.ID, Body: dbDraft.Body, CreatedAt: dbDraft.CreatedAt, UpdatedAt: dbDraft.UpdatedAt}
+	if dbDraft.InReplyToID.Valid {
+		d.InReplyToID = &dbDraft.InReplyToID.UUID
+	}
+	if dbDraft.QuoteOfID.Valid {
+		d.QuoteOfID = &dbDraft.QuoteOfID.UUID
+	}
+	return d
+}
+
+func decodeDraft(r *http.Request) (draftParameters, error) {
+	params := draftParameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		return params, errors.New("Invalid request body")
+	}
+	if len(params.Body) > maxDraftLength {
+		return params, fmt.Errorf("Draft length must be no greater than %d characters", maxDraftLength)
+	}
+	return params, nil
+}
+
+func (cfg *apiConfig) createDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	params, err := decodeDraft(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	count, err := cfg.dbQueries.CountDrafts(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if count >= maxDrafts {
+		respondWithText(w, 400, fmt.Sprintf("You can have at most %d drafts", maxDrafts))
+		return
+	}
+	dbDraft, err := cfg.dbQueries.CreateDraft(r.Context(), database.CreateDraftParams{UserID: userID, Body: params.Body, InReplyToID: params.InReplyTo, QuoteOfID: params.QuoteOf})
+	if err != nil {
+		fmt.Printf("Error creating draft:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 201, draft(dbDraft))
+}
+
+func (cfg *apiConfig) draftsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	dbDrafts, err := cfg.dbQueries.GetDrafts(r.Context(), userID)
+	if err != nil {
+		fmt.Printf("Error getting drafts:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	drafts := make([]Draft, 0, len(dbDrafts))
+	for _, dbDraft := range dbDrafts {
+		drafts = append(drafts, draft(dbDraft))
+	}
+	respondWithJSON(w, 200, drafts)
+}
+
+func (cfg *apiConfig) getDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	dbDraft, err := cfg.dbQueries.GetDraft(r.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, draft(dbDraft))
+}
+
+func (cfg *apiConfig) updateDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	params, err := decodeDraft(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	update := database.UpdateDraftParams{ID: draftID, UserID: userID, Body: params.Body, InReplyToID: params.InReplyTo, QuoteOfID: params.QuoteOf}
+	if params.UpdatedAt != nil {
+		update.LastUpdatedAt.Time = params.UpdatedAt.UTC()
+		update.LastUpdatedAt.Valid = true
+	}
+	dbDraft, err := cfg.dbQueries.UpdateDraft(r.Context(), update)
+	if err == nil {
+		respondWithJSON(w, 200, draft(dbDraft))
+		return
+	}
+	if !strings.Contains(err.Error(), "no rows in result set") {
+		fmt.Printf("Error saving draft:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	// no row matched: either the draft is gone or it was saved elsewhere,
+	// in which case the client gets the current version to reconcile with
+	dbDraft, err = cfg.dbQueries.GetDraft(r.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 409, draft(dbDraft))
+}
+
+func (cfg *apiConfig) deleteDraftHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	n, err := cfg.dbQueries.DeleteDraft(r.Context(), database.DeleteDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		fmt.Printf("Error deleting draft:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// publishDraftHandler turns a draft into a chirp, validated exactly like one
+// posted directly. The draft is deleted in the same transaction, so it is
+// either published and gone or left untouched.
+func (cfg *apiConfig) publishDraftHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		MediaIDs   []uuid.UUID `json:"media_ids"`
+		Poll       *pollInput  `json:"poll"`
+		PublishAt  time.Time   `json:"publish_at"`
+		Visibility string      `json:"visibility"`
+		// the solved challenge, when a previous attempt asked for one
+		Verification *verificationInput `json:"verification"`
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	draftID, err := uuid.Parse(r.PathValue("draftID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	// the body is optional
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil && err != io.EOF {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	dbDraft, err := cfg.dbQueries.GetDraft(r.Context(), database.GetDraftParams{ID: draftID, UserID: userID})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	dbChirp, err := cfg.createChirp(r.Context(), userID, chirpInput{Body: dbDraft.Body, InReplyTo: dbDraft.InReplyToID, QuoteOf: dbDraft.QuoteOfID, MediaIDs: params.MediaIDs, Poll: params.Poll, PublishAt: params.PublishAt, Visibility: params.Visibility, Draft: &dbDraft, Verification: params.Verification})
+	if err != nil {
+		var verifyErr *verificationError
+		if errors.As(err, &verifyErr) {
+			respondWithChallenge(w, verifyErr)
+			return
+		}
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Printf("Error publishing draft:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	cfg.respondWithOwnChirp(w, r, 201, userID, dbChirp)
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/entities"
+	"github.com/leiper-mike/chirpy/internal/spam"
+)
+
+const (
+	defaultEditWindow = time.Hour
+	maxChirpEdits     = 10
+)
+
+type ChirpRevision struct {
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChirpHistory is a chirp as it is now plus its earlier versions, newest first.
+type ChirpHistory struct {
+	Chirp     Chirp           `json:"chirp"`
+	Revisions []ChirpRevision `json:"revisions"`
+}
+
+func parseEditWindow(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultEditWindow, nil
+	}
+	window, err := time.ParseDuration(s)
+	if err != nil || window < 0 {
+		return 0, fmt.Errorf("invalid CHIRP_EDIT_WINDOW %q, expected a duration such as 30m", s)
+	}
+	return window, nil
+}
+
+// versionTime is when the current body of a chirp was written.
+func versionTime(dbChirp database.Chirp) time.Time {
+	if dbChirp.EditedAt.Valid {
+		return dbChirp.EditedAt.Time
+	}
+	return dbChirp.CreatedAt.Time
+}
+
+func (cfg *apiConfig) editChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body string `json:"body"`
+		// the solved challenge, when a previous attempt asked for one
+		Verification *verificationInput `json:"verification"`
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	err = validateChirpBody(params.Body)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	var dbChirp database.Chirp
+	hasLinks := false
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		dbChirp, hasLinks, err = cfg.editChirp(r.Context(), q, userID, chirpID, params.Body, params.Verification)
+		return err
+	})
+	if err != nil {
+		var verifyErr *verificationError
+		if errors.As(err, &verifyErr) {
+			respondWithChallenge(w, verifyErr)
+			return
+		}
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Printf("Error editing chirp:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if hasLinks {
+		cfg.wakeUnfurler()
+	}
+	cfg.respondWithOwnChirp(w, r, 200, userID, dbChirp)
+}
+
+// editChirp replaces a chirp's body, saving the old one as a revision. The
+// chirp is locked first so concurrent edits each record the version they replace.
+// The new body is screened for spam like a new chirp, v answering a challenge.
+func (cfg *apiConfig) editChirp(ctx context.Context, q *database.Queries, userID, chirpID uuid.UUID, body string, v *verificationInput) (database.Chirp, bool, error) {
+	current, err := q.LockChirp(ctx, chirpID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return database.Chirp{}, false, &requestError{404, "Chirp not found"}
+		}
+		return database.Chirp{}, false, err
+	}
+	visible, err := canView(ctx, q, current, userID)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	if current.DeletedAt.Valid || !visible {
+		return database.Chirp{}, false, &requestError{404, "Chirp not found"}
+	}
+	if current.UserID != userID {
+		return database.Chirp{}, false, &requestError{403, "You can only edit your own chirps"}
+	}
+	if current.PublishAt.Valid {
+		return database.Chirp{}, false, &requestError{400, "Scheduled chirps are edited through /api/scheduled_chirps"}
+	}
+	if current.HiddenAt.Valid {
+		return database.Chirp{}, false, &requestError{403, "This chirp is under review and can't be edited"}
+	}
+	if current.RechirpOfID.Valid {
+		return database.Chirp{}, false, &requestError{400, "A rechirp cannot be edited"}
+	}
+	if current.QuoteOfID.Valid && strings.TrimSpace(body) == "" {
+		return database.Chirp{}, false, &requestError{400, "A quote chirp must have a body"}
+	}
+	if time.Since(current.CreatedAt.Time) > cfg.editWindow {
+		return database.Chirp{}, false, &requestError{403, "This chirp can no longer be edited"}
+	}
+	if body == current.Body {
+		return current, false, nil
+	}
+	edits, err := q.CountChirpRevisions(ctx, chirpID)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	if edits >= maxChirpEdits {
+		return database.Chirp{}, false, &requestError{403, fmt.Sprintf("A chirp can be edited at most %d times", maxChirpEdits)}
+	}
+	verdict, err := cfg.screenChirp(ctx, userID, chirpID, body, v)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	err = q.CreateChirpRevision(ctx, database.CreateChirpRevisionParams{ChirpID: chirpID, Body: current.Body, CreatedAt: versionTime(current)})
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	dbChirp, err := q.EditChirp(ctx, database.EditChirpParams{ID: chirpID, Body: body})
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	err = recordSpamCheck(ctx, q, userID, uuid.NullUUID{UUID: chirpID, Valid: true}, body, verdict, true)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	// a held edit takes the chirp down until a moderator approves it, and
+	// isn't indexed until then
+	if verdict.Action == spam.Hold {
+		_, err = q.HideChirp(ctx, chirpID)
+		if err != nil {
+			return database.Chirp{}, false, err
+		}
+		dbChirp.HiddenAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+		return dbChirp, false, publishChirpEvent(ctx, q, streamEventDeleted, dbChirp)
+	}
+	hasLinks, err := cfg.reindexChirp(ctx, q, dbChirp)
+	if err != nil {
+		return database.Chirp{}, false, err
+	}
+	return dbChirp, hasLinks, publishChirpEvent(ctx, q, streamEventEdited, dbChirp)
+}
+
+// reindexChirp brings the hashtags, mentions and links of an edited chirp in
+// line with its new body. Only users mentioned for the first time are notified.
+func (cfg *apiConfig) reindexChirp(ctx context.Context, q *database.Queries, dbChirp database.Chirp) (bool, error) {
+	err := q.UntagChirp(ctx, database.UntagChirpParams{ChirpID: dbChirp.ID, Tags: entities.Unique(entities.Hashtags(dbChirp.Body))})
+	if err != nil {
+		return false, err
+	}
+	err = tagChirp(ctx, q, dbChirp)
+	if err != nil {
+		return false, err
+	}
+	err = q.RemoveChirpMentions(ctx, database.RemoveChirpMentionsParams{ChirpID: dbChirp.ID, Handles: entities.Unique(entities.Mentions(dbChirp.Body))})
+	if err != nil {
+		return false, err
+	}
+	// existing mentions are left alone by the insert, so these are all new
+	mentioned, err := mentionChirp(ctx, q, dbChirp)
+	if err != nil {
+		return false, err
+	}
+	for _, mentionedID := range mentioned {
+		err = cfg.notify(ctx, q, mentionedID, dbChirp.UserID, notificationMention, dbChirp.ID)
+		if err != nil {
+			return false, err
+		}
+	}
+	err = q.ClearChirpLinks(ctx, dbChirp.ID)
+	if err != nil {
+		return false, err
+	}
+	return linkChirp(ctx, q, dbChirp)
+}
+
+func (cfg *apiConfig) chirpHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	viewerID := cfg.viewerID(r)
+	visible, err := canView(r.Context(), cfg.dbQueries, dbChirp, viewerID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbChirp.DeletedAt.Valid || !visible {
+		w.WriteHeader(404)
+		return
+	}
+	dbRevisions, err := cfg.dbQueries.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		fmt.Printf("Error getting revisions:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	chirp, err := cfg.renderChirp(r.Context(), viewerID, dbChirp)
+	if err != nil {
+		fmt.Printf("Error rendering chirp:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	history := ChirpHistory{Chirp: chirp, Revisions: make([]ChirpRevision, 0, len(dbRevisions))}
+	for _, dbRevision := range dbRevisions {
+		history.Revisions = append(history.Revisions, ChirpRevision{Body: dbRevision.Body, CreatedAt: dbRevision.CreatedAt})
+	}
+	respondWithJSON(w, 200, history)
+}
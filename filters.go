@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/filter"
+)
+
+const maxMutedWords = 200
+
+type MutedWord struct {
+	ID        uuid.UUID  `json:"id"`
+	Kind      string     `json:"kind"`
+	Pattern   string     `json:"pattern"`
+	Action    string     `json:"action"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// FilterMatch is set on a chirp the viewer's muted words collapse behind a
+// warning rather than hide.
+type FilterMatch struct {
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+}
+
+func mutedWord(dbWord database.MutedWord) MutedWord {
+	word := MutedWord{ID: dbWord.ID, Kind: dbWord.Kind, Pattern: dbWord.Pattern, Action: dbWord.Action, CreatedAt: dbWord.CreatedAt}
+	if dbWord.ExpiresAt.Valid {
+		word.ExpiresAt = &dbWord.ExpiresAt.Time
+	}
+	return word
+}
+
+func (cfg *apiConfig) mutedWordsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	dbWords, err := cfg.dbQueries.GetMutedWords(r.Context(), userID)
+	if err != nil {
+		fmt.Printf("Error getting muted words:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	words := make([]MutedWord, 0, len(dbWords))
+	for _, dbWord := range dbWords {
+		words = append(words, mutedWord(dbWord))
+	}
+	respondWithJSON(w, 200, words)
+}
+
+func (cfg *apiConfig) createMutedWordHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	type parameters struct {
+		Kind      string     `json:"kind"`
+		Pattern   string     `json:"pattern"`
+		Action    string     `json:"action"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	rule, err := filter.Normalize(filter.Rule{Kind: params.Kind, Pattern: params.Pattern, Action: params.Action})
+	if err != nil {
+		respondWithText(w, 400, "Invalid muted word: "+err.Error())
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			respondWithText(w, 400, "expires_at must be in the future")
+			return
+		}
+		expiresAt = sql.NullTime{Time: params.ExpiresAt.UTC(), Valid: true}
+	}
+	err = cfg.dbQueries.DeleteExpiredMutedWords(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	count, err := cfg.dbQueries.CountMutedWords(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if count >= maxMutedWords {
+		respondWithText(w, 400, fmt.Sprintf("You can have at most %d muted words", maxMutedWords))
+		return
+	}
+	dbWord, err := cfg.dbQueries.CreateMutedWord(r.Context(), database.CreateMutedWordParams{UserID: userID, Kind: rule.Kind, Pattern: rule.Pattern, Action: rule.Action, ExpiresAt: expiresAt})
+	if err != nil {
+		fmt.Printf("Error creating muted word:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 201, mutedWord(dbWord))
+}
+
+func (cfg *apiConfig) deleteMutedWordHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	wordID, err := uuid.Parse(r.PathValue("wordID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	n, err := cfg.dbQueries.DeleteMutedWord(r.Context(), database.DeleteMutedWordParams{ID: wordID, UserID: userID})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// mutedWordFilter builds the viewer's filter from their unexpired muted
+// words, once per request. Anonymous viewers get an empty one.
+func (cfg *apiConfig) mutedWordFilter(ctx context.Context, viewerID uuid.UUID) (*filter.Filter, error) {
+	if viewerID == uuid.Nil {
+		return nil, nil
+	}
+	dbWords, err := cfg.dbQueries.GetMutedWords(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]filter.Rule, 0, len(dbWords))
+	for _, dbWord := range dbWords {
+		rules = append(rules, filter.Rule{Kind: dbWord.Kind, Pattern: dbWord.Pattern, Action: dbWord.Action})
+	}
+	return filter.New(rules)
+}
+
+// filterChirps drops the chirps f hides and marks the ones it collapses. A
+// rechirp or quote is judged by the embedded chirp's text as well as its
+// own; the viewer's own chirps are left alone.
+func filterChirps(f *filter.Filter, viewerID uuid.UUID, chirps []Chirp) []Chirp {
+	if f.Empty() {
+		return chirps
+	}
+	kept := make([]Chirp, 0, len(chirps))
+	for _, chirp := range chirps {
+		rule, ok := matchChirp(f, viewerID, chirp)
+		if ok && rule.Action == filter.Hide {
+			continue
+		}
+		if ok {
+			chirp.Filtered = &FilterMatch{Kind: rule.Kind, Pattern: rule.Pattern}
+		}
+		kept = append(kept, chirp)
+	}
+	return kept
+}
+
+func matchChirp(f *filter.Filter, viewerID uuid.UUID, chirp Chirp) (filter.Rule, bool) {
+	var found filter.Rule
+	matched := false
+	for _, c := range []*Chirp{&chirp, chirp.RechirpOf, chirp.QuoteOf} {
+		if c == nil || c.UserID == viewerID {
+			continue
+		}
+		rule, ok := f.Match(c.Body)
+		if ok && rule.Action == filter.Hide {
+			return rule, true
+		}
+		if ok && !matched {
+			found, matched = rule, true
+		}
+	}
+	return found, matched
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+type UserList struct {
+	Users      []UserSummary `json:"users"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) followHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	followee, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if followee.ID == userID {
+		respondWithText(w, 400, "You cannot follow yourself")
+		return
+	}
+	blocked, err := cfg.dbQueries.BlockExists(r.Context(), database.BlockExistsParams{UserIds: []uuid.UUID{followee.ID}, UserID: userID})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if blocked {
+		respondWithText(w, 403, "You cannot follow this user")
+		return
+	}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		n, err := q.FollowUser(r.Context(), database.FollowUserParams{FollowerID: userID, FolloweeID: followee.ID})
+		if err != nil || n == 0 {
+			return err
+		}
+		err = cfg.notify(r.Context(), q, followee.ID, userID, notificationFollow, uuid.Nil)
+		if err != nil {
+			return err
+		}
+		return cfg.timeline.followed(r.Context(), q, userID, followee.ID)
+	})
+	if err != nil {
+		fmt.Printf("Error following user: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) unfollowHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	followee, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		n, err := q.UnfollowUser(r.Context(), database.UnfollowUserParams{FollowerID: userID, FolloweeID: followee.ID})
+		if err != nil || n == 0 {
+			return err
+		}
+		return cfg.timeline.unfollowed(r.Context(), q, userID, followee.ID)
+	})
+	if err != nil {
+		fmt.Printf("Error unfollowing user: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) followersHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(ctx context.Context, userID uuid.UUID, cursor pageCursor, limit int32) ([]database.GetFollowersRow, error) {
+		return cfg.dbQueries.GetFollowers(ctx, database.GetFollowersParams{UserID: userID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+	})
+}
+
+func (cfg *apiConfig) followingHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.listFollows(w, r, func(ctx context.Context, userID uuid.UUID, cursor pageCursor, limit int32) ([]database.GetFollowersRow, error) {
+		rows, err := cfg.dbQueries.GetFollowing(ctx, database.GetFollowingParams{UserID: userID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+		if err != nil {
+			return nil, err
+		}
+		converted := make([]database.GetFollowersRow, 0, len(rows))
+		for _, row := range rows {
+			converted = append(converted, database.GetFollowersRow(row))
+		}
+		return converted, nil
+	})
+}
+
+func (cfg *apiConfig) listFollows(w http.ResponseWriter, r *http.Request, fetch func(context.Context, uuid.UUID, pageCursor, int32) ([]database.GetFollowersRow, error)) {
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	rows, err := fetch(r.Context(), dbUser.ID, cursor, limit)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	list := UserList{Users: make([]UserSummary, 0, len(rows))}
+	for _, row := range rows {
+		list.Users = append(list.Users, *userSummary(row.ID, row.Handle, row.DisplayName, row.AvatarKey))
+	}
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		list.NextCursor = nextCursor(len(rows), limit, pageCursor{Time: last.FollowedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, list)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/entities"
+	"github.com/leiper-mike/chirpy/internal/trending"
+)
+
+const (
+	trendingInterval = time.Minute
+	trendingMinCount = 3
+	trendingSize     = 20
+)
+
+var trendingWindows = []struct {
+	name   string
+	length time.Duration
+}{
+	{"1h", time.Hour},
+	{"24h", 24 * time.Hour},
+}
+
+type TrendingHashtag struct {
+	Tag        string  `json:"tag"`
+	ChirpCount int32   `json:"chirp_count"`
+	Score      float64 `json:"score"`
+}
+
+type TrendingHashtags struct {
+	Window     string            `json:"window"`
+	ComputedAt *time.Time        `json:"computed_at,omitempty"`
+	Hashtags   []TrendingHashtag `json:"hashtags"`
+}
+
+// tagChirp records the hashtags in a new chirp's body.
+func tagChirp(ctx context.Context, q *database.Queries, dbChirp database.Chirp) error {
+	tags := entities.Unique(entities.Hashtags(dbChirp.Body))
+	if len(tags) == 0 {
+		return nil
+	}
+	return q.TagChirp(ctx, database.TagChirpParams{Tags: tags, ChirpID: dbChirp.ID})
+}
+
+func (cfg *apiConfig) hashtagChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	tag := entities.NormalizeTag(r.PathValue("tag"))
+	viewerID := cfg.viewerID(r)
+	dbChirps, err := cfg.dbQueries.GetHashtagChirps(r.Context(), database.GetHashtagChirpsParams{Tag: tag, ViewerID: viewerID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+	if err != nil {
+		fmt.Printf("Error getting chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	cfg.respondWithChirpPage(w, r.Context(), viewerID, dbChirps, limit)
+}
+
+func (cfg *apiConfig) trendingHashtagsHandler(w http.ResponseWriter, r *http.Request) {
+	window := r.URL.Query().Get("window")
+	if window == "" {
+		window = trendingWindows[0].name
+	}
+	valid := false
+	for _, tw := range trendingWindows {
+		valid = valid || tw.name == window
+	}
+	if !valid {
+		respondWithText(w, 400, "Unknown window, expected 1h or 24h")
+		return
+	}
+	rows, err := cfg.dbQueries.GetTrendingHashtags(r.Context(), window)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	resp := TrendingHashtags{Window: window, Hashtags: make([]TrendingHashtag, 0, len(rows))}
+	for _, row := range rows {
+		resp.ComputedAt = &row.ComputedAt
+		resp.Hashtags = append(resp.Hashtags, TrendingHashtag{Tag: row.Tag, ChirpCount: row.ChirpCount, Score: row.Score})
+	}
+	respondWithJSON(w, 200, resp)
+}
+
+// runTrendingWorker periodically recomputes trending tags for every window.
+// Results are stored in the database so every instance serves the same list.
+func (cfg *apiConfig) runTrendingWorker(ctx context.Context) {
+	ticker := time.NewTicker(trendingInterval)
+	defer ticker.Stop()
+	for {
+		for _, w := range trendingWindows {
+			err := cfg.refreshTrending(ctx, w.name, w.length)
+			if err != nil {
+				fmt.Printf("Error refreshing trending hashtags for %s: %v\n", w.name, err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) refreshTrending(ctx context.Context, window string, length time.Duration) error {
+	rows, err := cfg.dbQueries.GetHashtagUsage(ctx, int32(length.Seconds()))
+	if err != nil {
+		return err
+	}
+	usages := make([]trending.Usage, 0, len(rows))
+	for _, row := range rows {
+		usages = append(usages, trending.Usage{Tag: row.Tag, Current: int(row.CurrentCount), Previous: int(row.PreviousCount)})
+	}
+	ranked := trending.Rank(usages, trendingMinCount, trendingSize)
+	return cfg.inTx(ctx, func(q *database.Queries) error {
+		err := q.ClearTrendingHashtags(ctx, window)
+		if err != nil {
+			return err
+		}
+		for i, tag := range ranked {
+			err = q.InsertTrendingHashtag(ctx, database.InsertTrendingHashtagParams{WindowName: window, Rank: int32(i + 1), Tag: tag.Tag, ChirpCount: int32(tag.Count), Score: tag.Score})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
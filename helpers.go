@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/auth"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	dat, err := json.Marshal(payload)
+	if err != nil {
+		fmt.Printf("Error marshalling JSON: %s", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(dat)
+}
+
+func respondWithText(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write([]byte(msg))
+}
+
+func (cfg *apiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return auth.ValidateJWT(token, cfg.secret)
+}
+
+// viewerID returns the authenticated caller for endpoints that also serve
+// anonymous requests, or uuid.Nil when there is no valid token.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.UUID {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		return uuid.Nil
+	}
+	return userID
+}
+
+// inTx runs fn against a transaction, committing if it returns nil.
+func (cfg *apiConfig) inTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(cfg.dbQueries.WithTx(tx))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -53,6 +53,64 @@ func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error){
 	}
 	return UUID, nil
 }
+// sessionClaims add the login session a token was issued for, as "sid".
+type sessionClaims struct{
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+}
+// Session is what a token says about the login it was issued for.
+type Session struct{
+	UserID uuid.UUID
+	// uuid.Nil for tokens without one
+	ID uuid.UUID
+	// zero for tokens that never expire
+	ExpiresAt time.Time
+}
+// MakeSessionJWT is MakeJWT for a token that also names the login session
+// it belongs to, so connections opened with it can be ended with the session.
+func MakeSessionJWT(userID, sessionID uuid.UUID, tokenSecret string) (string, error) {
+	dur, _ := time.ParseDuration("1h")
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, sessionClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer: "chirpy",
+			IssuedAt: jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().UTC().Add(dur)),
+			Subject: userID.String(),
+		},
+		SessionID: sessionID.String(),
+	})
+	return token.SignedString([]byte(tokenSecret))
+}
+// ValidateSessionJWT validates a token like ValidateJWT, also returning its
+// session and when it stops being valid, for long-lived connections that
+// have to enforce both themselves.
+func ValidateSessionJWT(tokenString, tokenSecret string) (Session, error){
+	claims := sessionClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(tokenSecret), nil
+	})
+	if err != nil{
+		fmt.Printf("Error parsing token: %v", err)
+		return Session{}, err
+	}
+	UUID, err := uuid.Parse(claims.Subject)
+	if err != nil{
+		fmt.Printf("Error parsing ID to UUID: %v", err)
+		return Session{}, err
+	}
+	session := Session{UserID: UUID}
+	if claims.SessionID != ""{
+		session.ID, err = uuid.Parse(claims.SessionID)
+		if err != nil{
+			fmt.Printf("Error parsing session ID to UUID: %v", err)
+			return Session{}, err
+		}
+	}
+	if claims.ExpiresAt != nil{
+		session.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return session, nil
+}
 func GetBearerToken(headers http.Header) (string, error){
 	authHeader := headers.Get("Authorization")
 	if authHeader == ""{
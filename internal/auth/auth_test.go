@@ -3,6 +3,7 @@ package auth
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -64,6 +65,43 @@ func TestMakeJWT(t *testing.T){
 		t.Errorf("Validated invalid secret")
 	}
 }
+func TestSessionJWT(t *testing.T){
+	id, sessionID := uuid.New(), uuid.New()
+	token, err := MakeSessionJWT(id, sessionID, "superSecret")
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	session, err := ValidateSessionJWT(token, "superSecret")
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if session.UserID != id || session.ID != sessionID {
+		t.Errorf("Recieved user %v and session %v, expected %v and %v", session.UserID, session.ID, id, sessionID)
+	}
+	if until := time.Until(session.ExpiresAt); until <= 59*time.Minute || until > time.Hour {
+		t.Errorf("Recieved expiry %v from now, expected about an hour", until)
+	}
+	if gotID, err := ValidateJWT(token, "superSecret"); err != nil || gotID != id {
+		t.Errorf("ValidateJWT should accept session tokens, recieved %v and %v", gotID, err)
+	}
+	_, err = ValidateSessionJWT(token, "superDuperSecret")
+	if err == nil {
+		t.Errorf("Validated invalid secret")
+	}
+	// tokens without an expiry or session are valid, and it's up to the
+	// caller to refuse them
+	forever, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: id.String()}).SignedString([]byte("superSecret"))
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	if _, err = ValidateJWT(forever, "superSecret"); err != nil {
+		t.Errorf("ValidateJWT rejected a token without an expiry: %v", err)
+	}
+	session, err = ValidateSessionJWT(forever, "superSecret")
+	if err != nil || !session.ExpiresAt.IsZero() || session.ID != uuid.Nil {
+		t.Errorf("Recieved %+v and error %v, expected no expiry or session", session, err)
+	}
+}
 func TestGetBearerToken(t *testing.T){
 	req, err := http.NewRequest(http.MethodGet,"fake.com",nil)
 	if err != nil{
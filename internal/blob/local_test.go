@@ -0,0 +1,51 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+	err = store.Put(ctx, "avatars/abc/48.jpg", strings.NewReader("hello"), "image/jpeg")
+	if err != nil {
+		t.Fatalf("Failed to put blob: %v", err)
+	}
+	obj, err := store.Get(ctx, "avatars/abc/48.jpg")
+	if err != nil {
+		t.Fatalf("Failed to get blob: %v", err)
+	}
+	dat, _ := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if string(dat) != "hello" {
+		t.Errorf("Recieved %q, expected hello", dat)
+	}
+	if obj.ContentType != "image/jpeg" {
+		t.Errorf("Recieved content type %v, expected image/jpeg", obj.ContentType)
+	}
+	err = store.Delete(ctx, "avatars/abc/48.jpg")
+	if err != nil {
+		t.Errorf("Failed to delete blob: %v", err)
+	}
+	_, err = store.Get(ctx, "avatars/abc/48.jpg")
+	if err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, recieved %v", err)
+	}
+}
+
+func TestValidateKey(t *testing.T) {
+	for _, key := range []string{"", "/etc/passwd", "../secret", "a/../../b", "a//b", "a\\b"} {
+		if ValidateKey(key) == nil {
+			t.Errorf("Key %q should be rejected", key)
+		}
+	}
+	if err := ValidateKey("avatars/abc/48.jpg"); err != nil {
+		t.Errorf("Valid key rejected: %v", err)
+	}
+}
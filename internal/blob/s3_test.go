@@ -0,0 +1,95 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The example request from the AWS Signature Version 4 documentation.
+func TestSignRequest(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	signRequest(req, nil, "us-east-1", "iam", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7"
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("Recieved %v, expected %v", got, expected)
+	}
+}
+
+// fakeS3 is an in-memory stand-in for a bucket that only checks requests are signed.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(403)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		dat, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = dat
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		dat, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(404)
+			return
+		}
+		w.Header().Set("Content-Type", f.types[r.URL.Path])
+		w.Write(dat)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(204)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	store, err := NewS3Store(S3Config{Endpoint: server.URL, Region: "us-east-1", Bucket: "chirpy", AccessKeyID: "key", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	ctx := context.Background()
+	err = store.Put(ctx, "media/abc/thumb.jpg", strings.NewReader("hello"), "image/jpeg")
+	if err != nil {
+		t.Fatalf("Failed to put blob: %v", err)
+	}
+	if _, ok := fake.objects["/chirpy/media/abc/thumb.jpg"]; !ok {
+		t.Errorf("Blob not stored under the bucket path: %v", fake.objects)
+	}
+	obj, err := store.Get(ctx, "media/abc/thumb.jpg")
+	if err != nil {
+		t.Fatalf("Failed to get blob: %v", err)
+	}
+	dat, _ := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if string(dat) != "hello" || obj.ContentType != "image/jpeg" {
+		t.Errorf("Recieved %q (%v), expected hello (image/jpeg)", dat, obj.ContentType)
+	}
+	err = store.Delete(ctx, "media/abc/thumb.jpg")
+	if err != nil {
+		t.Errorf("Failed to delete blob: %v", err)
+	}
+	_, err = store.Get(ctx, "media/abc/thumb.jpg")
+	if err != ErrNotFound {
+		t.Errorf("Expected ErrNotFound after delete, recieved %v", err)
+	}
+	_, err = store.Get(ctx, "../escape")
+	if err != ErrInvalidKey {
+		t.Errorf("Expected ErrInvalidKey, recieved %v", err)
+	}
+}
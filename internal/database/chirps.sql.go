@@ -7,29 +7,69 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const canViewChirp = `-- name: CanViewChirp :one
+SELECT can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, $1::uuid) AS visible
+FROM chirps WHERE chirps.id = $2
+`
+
+type CanViewChirpParams struct {
+	ViewerID uuid.UUID
+	ChirpID  uuid.UUID
+}
+
+func (q *Queries) CanViewChirp(ctx context.Context, arg CanViewChirpParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, canViewChirp, arg.ViewerID, arg.ChirpID)
+	var visible bool
+	err := row.Scan(&visible)
+	return visible, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, conversation_id, rechirp_of_id, quote_of_id, publish_at, visibility)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
 )
-RETURNING id, body, user_id, created_at, updated_at
+RETURNING id, body, user_id, created_at, updated_at, in_reply_to_id, conversation_id, reply_count, deleted_at, like_count, rechirp_of_id, quote_of_id, rechirp_count, publish_at, edited_at, visibility, hidden_at, search, publish_attempts, next_attempt_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body           string
+	UserID         uuid.UUID
+	InReplyToID    uuid.NullUUID
+	ConversationID uuid.NullUUID
+	RechirpOfID    uuid.NullUUID
+	QuoteOfID      uuid.NullUUID
+	PublishAt      sql.NullTime
+	Visibility     string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyToID,
+		arg.ConversationID,
+		arg.RechirpOfID,
+		arg.QuoteOfID,
+		arg.PublishAt,
+		arg.Visibility,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,10 +77,43 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.InReplyToID,
+		&i.ConversationID,
+		&i.ReplyCount,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.RechirpCount,
+		&i.PublishAt,
+		&i.EditedAt,
+		&i.Visibility,
+		&i.HiddenAt,
+		&i.Search,
+		&i.PublishAttempts,
+		&i.NextAttemptAt,
 	)
 	return i, err
 }
 
+const decrementRechirpCount = `-- name: DecrementRechirpCount :exec
+UPDATE chirps SET rechirp_count = GREATEST(rechirp_count - 1, 0) WHERE chirps.id = $1
+`
+
+func (q *Queries) DecrementRechirpCount(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, decrementRechirpCount, id)
+	return err
+}
+
+const decrementReplyCount = `-- name: DecrementReplyCount :exec
+UPDATE chirps SET reply_count = GREATEST(reply_count - 1, 0) WHERE chirps.id = $1
+`
+
+func (q *Queries) DecrementReplyCount(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, decrementReplyCount, id)
+	return err
+}
+
 const deleteChirp = `-- name: DeleteChirp :exec
 DELETE FROM chirps WHERE chirps.id = $1
 `
@@ -51,11 +124,13 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, body, user_id, created_at, updated_at FROM chirps
+SELECT id, body, user_id, created_at, updated_at, in_reply_to_id, conversation_id, reply_count, deleted_at, like_count, rechirp_of_id, quote_of_id, rechirp_count, publish_at, edited_at, visibility, hidden_at, search, publish_attempts, next_attempt_at FROM chirps
+WHERE chirps.deleted_at IS NULL AND chirps.publish_at IS NULL
+AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, $1::uuid)
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+func (q *Queries) GetAllChirps(ctx context.Context, viewerID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirps, viewerID)
 	if err != nil {
 		return nil, err
 	}
@@ -69,6 +144,21 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UserID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.InReplyToID,
+			&i.ConversationID,
+			&i.ReplyCount,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.PublishAt,
+			&i.EditedAt,
+			&i.Visibility,
+			&i.HiddenAt,
+			&i.Search,
+			&i.PublishAttempts,
+			&i.NextAttemptAt,
 		); err != nil {
 			return nil, err
 		}
@@ -84,7 +174,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, body, user_id, created_at, updated_at FROM chirps WHERE chirps.id = $1
+SELECT id, body, user_id, created_at, updated_at, in_reply_to_id, conversation_id, reply_count, deleted_at, like_count, rechirp_of_id, quote_of_id, rechirp_count, publish_at, edited_at, visibility, hidden_at, search, publish_attempts, next_attempt_at FROM chirps WHERE chirps.id = $1
 `
 
 func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -96,6 +186,178 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UserID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.InReplyToID,
+		&i.ConversationID,
+		&i.ReplyCount,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.RechirpCount,
+		&i.PublishAt,
+		&i.EditedAt,
+		&i.Visibility,
+		&i.HiddenAt,
+		&i.Search,
+		&i.PublishAttempts,
+		&i.NextAttemptAt,
+	)
+	return i, err
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, body, user_id, created_at, updated_at, in_reply_to_id, conversation_id, reply_count, deleted_at, like_count, rechirp_of_id, quote_of_id, rechirp_count, publish_at, edited_at, visibility, hidden_at, search, publish_attempts, next_attempt_at FROM chirps WHERE chirps.id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, ids []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyToID,
+			&i.ConversationID,
+			&i.ReplyCount,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.PublishAt,
+			&i.EditedAt,
+			&i.Visibility,
+			&i.HiddenAt,
+			&i.Search,
+			&i.PublishAttempts,
+			&i.NextAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirpsByIDs = `-- name: GetVisibleChirpsByIDs :many
+SELECT id, body, user_id, created_at, updated_at, in_reply_to_id, conversation_id, reply_count, deleted_at, like_count, rechirp_of_id, quote_of_id, rechirp_count, publish_at, edited_at, visibility, hidden_at, search, publish_attempts, next_attempt_at FROM chirps
+WHERE chirps.id = ANY($1::uuid[])
+AND chirps.publish_at IS NULL
+AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, $2::uuid)
+`
+
+type GetVisibleChirpsByIDsParams struct {
+	Ids      []uuid.UUID
+	ViewerID uuid.UUID
+}
+
+func (q *Queries) GetVisibleChirpsByIDs(ctx context.Context, arg GetVisibleChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getVisibleChirpsByIDs, pq.Array(arg.Ids), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.Body,
+			&i.UserID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.InReplyToID,
+			&i.ConversationID,
+			&i.ReplyCount,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpOfID,
+			&i.QuoteOfID,
+			&i.RechirpCount,
+			&i.PublishAt,
+			&i.EditedAt,
+			&i.Visibility,
+			&i.HiddenAt,
+			&i.Search,
+			&i.PublishAttempts,
+			&i.NextAttemptAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const incrementRechirpCount = `-- name: IncrementRechirpCount :exec
+UPDATE chirps SET rechirp_count = rechirp_count + 1 WHERE chirps.id = $1
+`
+
+func (q *Queries) IncrementRechirpCount(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, incrementRechirpCount, id)
+	return err
+}
+
+const incrementReplyCount = `-- name: IncrementReplyCount :exec
+UPDATE chirps SET reply_count = reply_count + 1 WHERE chirps.id = $1
+`
+
+func (q *Queries) IncrementReplyCount(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, incrementReplyCount, id)
+	return err
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :one
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+RETURNING id, body, user_id, created_at, updated_at, in_reply_to_id, conversation_id, reply_count, deleted_at, like_count, rechirp_of_id, quote_of_id, rechirp_count, publish_at, edited_at, visibility, hidden_at, search, publish_attempts, next_attempt_at
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, tombstoneChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.Body,
+		&i.UserID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.InReplyToID,
+		&i.ConversationID,
+		&i.ReplyCount,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpOfID,
+		&i.QuoteOfID,
+		&i.RechirpCount,
+		&i.PublishAt,
+		&i.EditedAt,
+		&i.Visibility,
+		&i.HiddenAt,
+		&i.Search,
+		&i.PublishAttempts,
+		&i.NextAttemptAt,
 	)
 	return i, err
 }
@@ -6,17 +6,226 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
 type Chirp struct {
+	ID              uuid.UUID
+	Body            string
+	UserID          uuid.UUID
+	CreatedAt       sql.NullTime
+	UpdatedAt       sql.NullTime
+	InReplyToID     uuid.NullUUID
+	ConversationID  uuid.NullUUID
+	ReplyCount      int32
+	DeletedAt       sql.NullTime
+	LikeCount       int32
+	RechirpOfID     uuid.NullUUID
+	QuoteOfID       uuid.NullUUID
+	RechirpCount    int32
+	PublishAt       sql.NullTime
+	EditedAt        sql.NullTime
+	Visibility      string
+	HiddenAt        sql.NullTime
+	Search          interface{}
+	PublishAttempts int32
+	NextAttemptAt   sql.NullTime
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	HashtagID uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpLink struct {
+	ChirpID  uuid.UUID
+	Url      string
+	Position int32
+}
+
+type ChirpMedium struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	ChirpID      uuid.NullUUID
+	Position     int32
+	Kind         string
+	ContentType  string
+	OriginalKey  string
+	ThumbnailKey string
+	Width        int32
+	Height       int32
+	SizeBytes    int64
+	AltText      string
+	CreatedAt    time.Time
+}
+
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+	Handle  string
+}
+
+type ChirpRevision struct {
 	ID        uuid.UUID
+	ChirpID   uuid.UUID
 	Body      string
+	CreatedAt time.Time
+}
+
+type Conversation struct {
+	ID        uuid.UUID
+	DirectKey sql.NullString
+	Title     string
+	CreatorID uuid.NullUUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type ConversationMember struct {
+	ConversationID uuid.UUID
+	UserID         uuid.UUID
+	JoinedAt       time.Time
+	LastReadAt     time.Time
+	LeftAt         sql.NullTime
+}
+
+type Draft struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Body        string
+	InReplyToID uuid.NullUUID
+	QuoteOfID   uuid.NullUUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type Hashtag struct {
+	ID        uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type LinkPreview struct {
+	Url           string
+	Status        string
+	Title         string
+	Description   string
+	ImageUrl      string
+	SiteName      string
+	Attempts      int32
+	NextAttemptAt time.Time
+	FetchedAt     sql.NullTime
+	CreatedAt     time.Time
+}
+
+type Message struct {
+	ID             uuid.UUID
+	ConversationID uuid.UUID
+	SenderID       uuid.UUID
+	Body           string
+	CreatedAt      time.Time
+}
+
+type ModerationAction struct {
+	ID            uuid.UUID
+	ModeratorID   uuid.NullUUID
+	ReportID      uuid.NullUUID
+	Action        string
+	TargetUserID  uuid.NullUUID
+	TargetChirpID uuid.NullUUID
+	Note          string
+	CreatedAt     time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type MutedWord struct {
+	ID        uuid.UUID
 	UserID    uuid.UUID
-	CreatedAt sql.NullTime
-	UpdatedAt sql.NullTime
+	Kind      string
+	Pattern   string
+	Action    string
+	ExpiresAt sql.NullTime
+	CreatedAt time.Time
+}
+
+type Notification struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Type       string
+	ChirpID    uuid.NullUUID
+	CreatedAt  time.Time
+	ReadAt     sql.NullTime
+	GroupKey   string
+	UpdatedAt  time.Time
+	ActorCount int32
+}
+
+type NotificationActor struct {
+	NotificationID uuid.UUID
+	ActorID        uuid.UUID
+	CreatedAt      time.Time
+}
+
+type NotificationPreference struct {
+	UserID  uuid.UUID
+	Type    string
+	Enabled bool
+}
+
+type Poll struct {
+	ChirpID         uuid.UUID
+	DurationSeconds int32
+	ClosesAt        sql.NullTime
+	ClosedAt        sql.NullTime
+	TotalVotes      int32
+	CreatedAt       time.Time
+}
+
+type PollOption struct {
+	ChirpID   uuid.UUID
+	Position  int32
+	Text      string
+	VoteCount int32
+}
+
+type PollVote struct {
+	ChirpID   uuid.UUID
+	UserID    uuid.UUID
+	Position  int32
+	CreatedAt time.Time
+}
+
+type RateLimit struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
 }
 
 type RefreshToken struct {
@@ -26,6 +235,63 @@ type RefreshToken struct {
 	UpdatedAt sql.NullTime
 	ExpiresAt time.Time
 	RevokedAt sql.NullTime
+	SessionID uuid.UUID
+}
+
+type Report struct {
+	ID         uuid.UUID
+	ReporterID uuid.UUID
+	UserID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	Reason     string
+	Details    string
+	Status     string
+	Resolution sql.NullString
+	AssignedTo uuid.NullUUID
+	ResolvedBy uuid.NullUUID
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+}
+
+type SpamCheck struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	ChirpID    uuid.NullUUID
+	Body       string
+	Score      float64
+	Action     string
+	Signals    json.RawMessage
+	Review     sql.NullString
+	ReviewedBy uuid.NullUUID
+	CreatedAt  time.Time
+	ReviewedAt sql.NullTime
+	Published  bool
+}
+
+type StreamEvent struct {
+	ID             int64
+	Type           string
+	ChirpID        uuid.NullUUID
+	Topics         []string
+	CreatedAt      time.Time
+	NotificationID uuid.NullUUID
+	MessageID      uuid.NullUUID
+}
+
+type TimelineEntry struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	AuthorID  uuid.UUID
+	CreatedAt time.Time
+}
+
+type TrendingHashtag struct {
+	WindowName string
+	Rank       int32
+	Tag        string
+	ChirpCount int32
+	Score      float64
+	ComputedAt time.Time
 }
 
 type User struct {
@@ -34,4 +300,11 @@ type User struct {
 	HashedPassword string
 	CreatedAt      sql.NullTime
 	UpdatedAt      sql.NullTime
+	Handle         sql.NullString
+	DisplayName    string
+	Bio            string
+	AvatarKey      sql.NullString
+	IsModerator    bool
+	SuspendedUntil sql.NullTime
+	AccountState   string
 }
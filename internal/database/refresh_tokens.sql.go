@@ -22,7 +22,7 @@ VALUES (
      $3,
      NULL
 )
-RETURNING token, user_id, created_at, updated_at, expires_at, revoked_at
+RETURNING token, user_id, created_at, updated_at, expires_at, revoked_at, session_id
 `
 
 type CreateRefreshTokenParams struct {
@@ -41,12 +41,13 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.UpdatedAt,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.SessionID,
 	)
 	return i, err
 }
 
 const getRefreshTokenByID = `-- name: GetRefreshTokenByID :one
-SELECT token, user_id, created_at, updated_at, expires_at, revoked_at FROM refresh_tokens
+SELECT token, user_id, created_at, updated_at, expires_at, revoked_at, session_id FROM refresh_tokens
 WHERE refresh_tokens.token = $1
 `
 
@@ -60,6 +61,7 @@ func (q *Queries) GetRefreshTokenByID(ctx context.Context, token string) (Refres
 		&i.UpdatedAt,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.SessionID,
 	)
 	return i, err
 }
@@ -74,3 +76,19 @@ func (q *Queries) RevokeToken(ctx context.Context, token string) error {
 	_, err := q.db.ExecContext(ctx, revokeToken, token)
 	return err
 }
+
+const sessionActive = `-- name: SessionActive :one
+SELECT EXISTS (
+    SELECT 1 FROM refresh_tokens
+    WHERE refresh_tokens.session_id = $1
+    AND refresh_tokens.revoked_at IS NULL
+    AND refresh_tokens.expires_at > NOW()
+) AS active
+`
+
+func (q *Queries) SessionActive(ctx context.Context, sessionID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, sessionActive, sessionID)
+	var active bool
+	err := row.Scan(&active)
+	return active, err
+}
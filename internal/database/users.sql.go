@@ -7,27 +7,40 @@ package database
 
 import (
 	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle, display_name)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
-RETURNING id, email, hashed_password, created_at, updated_at
+RETURNING id, email, hashed_password, created_at, updated_at, handle, display_name, bio, avatar_key, is_moderator, suspended_until, account_state
 `
 
 type CreateUserParams struct {
 	Email          string
 	HashedPassword string
+	Handle         sql.NullString
+	DisplayName    string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, createUser, arg.Email, arg.HashedPassword)
+	row := q.db.QueryRowContext(ctx, createUser,
+		arg.Email,
+		arg.HashedPassword,
+		arg.Handle,
+		arg.DisplayName,
+	)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -35,6 +48,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.HashedPassword,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.AccountState,
 	)
 	return i, err
 }
@@ -49,7 +69,7 @@ func (q *Queries) DeleteUsers(ctx context.Context) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, hashed_password, created_at, updated_at FROM users
+SELECT id, email, hashed_password, created_at, updated_at, handle, display_name, bio, avatar_key, is_moderator, suspended_until, account_state FROM users
 WHERE users.email = $1
 `
 
@@ -62,6 +82,174 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.HashedPassword,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.AccountState,
+	)
+	return i, err
+}
+
+const getUserByHandle = `-- name: GetUserByHandle :one
+SELECT id, email, hashed_password, created_at, updated_at, handle, display_name, bio, avatar_key, is_moderator, suspended_until, account_state FROM users
+WHERE users.handle = $1::text
+`
+
+func (q *Queries) GetUserByHandle(ctx context.Context, handle string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByHandle, handle)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.AccountState,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, hashed_password, created_at, updated_at, handle, display_name, bio, avatar_key, is_moderator, suspended_until, account_state FROM users
+WHERE users.id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.AccountState,
+	)
+	return i, err
+}
+
+const getUserSummaries = `-- name: GetUserSummaries :many
+SELECT id, handle, display_name, avatar_key FROM users
+WHERE users.id = ANY($1::uuid[])
+`
+
+type GetUserSummariesRow struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	AvatarKey   sql.NullString
+}
+
+func (q *Queries) GetUserSummaries(ctx context.Context, ids []uuid.UUID) ([]GetUserSummariesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserSummaries, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserSummariesRow
+	for rows.Next() {
+		var i GetUserSummariesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Handle,
+			&i.DisplayName,
+			&i.AvatarKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateUserAvatar = `-- name: UpdateUserAvatar :one
+UPDATE users
+SET avatar_key = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, hashed_password, created_at, updated_at, handle, display_name, bio, avatar_key, is_moderator, suspended_until, account_state
+`
+
+type UpdateUserAvatarParams struct {
+	ID        uuid.UUID
+	AvatarKey sql.NullString
+}
+
+func (q *Queries) UpdateUserAvatar(ctx context.Context, arg UpdateUserAvatarParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserAvatar, arg.ID, arg.AvatarKey)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.AccountState,
+	)
+	return i, err
+}
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $2, display_name = $3, bio = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, hashed_password, created_at, updated_at, handle, display_name, bio, avatar_key, is_moderator, suspended_until, account_state
+`
+
+type UpdateUserProfileParams struct {
+	ID          uuid.UUID
+	Handle      sql.NullString
+	DisplayName string
+	Bio         string
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile,
+		arg.ID,
+		arg.Handle,
+		arg.DisplayName,
+		arg.Bio,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.HashedPassword,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Handle,
+		&i.DisplayName,
+		&i.Bio,
+		&i.AvatarKey,
+		&i.IsModerator,
+		&i.SuspendedUntil,
+		&i.AccountState,
 	)
 	return i, err
 }
@@ -0,0 +1,125 @@
+package entities
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	maxTagLength    = 50
+	minHandleLength = 3
+	maxHandleLength = 15
+)
+
+// Entity is a #hashtag or @mention found in a chirp body. Start and End are
+// rune offsets into the body (End exclusive) covering the sigil, and Text is
+// the normalized (lowercased) value without the sigil.
+type Entity struct {
+	Start int
+	End   int
+	Text  string
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Hashtags returns every hashtag in body in order of appearance. A hashtag is
+// a # not preceded by a word character, followed by letters, digits or
+// underscores including at least one letter, so "#1" or "a#b" are ignored.
+func Hashtags(body string) []Entity {
+	return scan(body, '#', func(text []rune) bool {
+		if len(text) > maxTagLength {
+			return false
+		}
+		for _, r := range text {
+			if unicode.IsLetter(r) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Mentions returns every @handle in body in order of appearance. Handles are
+// 3-15 ASCII letters, digits or underscores; anything else is left as text.
+func Mentions(body string) []Entity {
+	return scan(body, '@', func(text []rune) bool {
+		if len(text) < minHandleLength || len(text) > maxHandleLength {
+			return false
+		}
+		for _, r := range text {
+			if r > unicode.MaxASCII {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// NormalizeTag lowercases a tag and strips a leading #.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// Unique returns the distinct texts of entities, keeping first appearance order.
+func Unique(found []Entity) []string {
+	seen := make(map[string]bool, len(found))
+	texts := []string{}
+	for _, e := range found {
+		if !seen[e.Text] {
+			seen[e.Text] = true
+			texts = append(texts, e.Text)
+		}
+	}
+	return texts
+}
+
+func scan(body string, sigil rune, valid func([]rune) bool) []Entity {
+	runes := []rune(body)
+	found := []Entity{}
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != sigil || (i > 0 && (isWordRune(runes[i-1]) || runes[i-1] == sigil)) {
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		text := runes[i+1 : end]
+		if len(text) > 0 && valid(text) {
+			found = append(found, Entity{Start: i, End: end, Text: strings.ToLower(string(text))})
+		}
+		i = end - 1
+	}
+	return found
+}
+
+// URLs returns every http(s) link in body in order of appearance. Text is the
+// link as written; trailing punctuation is assumed to belong to the sentence.
+func URLs(body string) []Entity {
+	runes := []rune(body)
+	found := []Entity{}
+	for i := 0; i < len(runes); i++ {
+		if i > 0 && !unicode.IsSpace(runes[i-1]) && runes[i-1] != '(' {
+			continue
+		}
+		rest := strings.ToLower(string(runes[i:min(i+8, len(runes))]))
+		if !strings.HasPrefix(rest, "http://") && !strings.HasPrefix(rest, "https://") {
+			continue
+		}
+		end := i
+		for end < len(runes) && !unicode.IsSpace(runes[end]) {
+			end++
+		}
+		for end > i && strings.ContainsRune(".,;:!?'\")", runes[end-1]) {
+			end--
+		}
+		text := string(runes[i:end])
+		if !strings.HasSuffix(strings.ToLower(text), "://") {
+			found = append(found, Entity{Start: i, End: end, Text: text})
+		}
+		i = end - 1
+	}
+	return found
+}
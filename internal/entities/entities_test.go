@@ -0,0 +1,64 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtags(t *testing.T) {
+	cases := []struct {
+		body     string
+		expected []Entity
+	}{
+		{"no tags here", []Entity{}},
+		{"#Go is fun", []Entity{{Start: 0, End: 3, Text: "go"}}},
+		{"love #chirpy_dev!", []Entity{{Start: 5, End: 16, Text: "chirpy_dev"}}},
+		{"issue #1 and a#b and ##double", []Entity{}},
+		{"héllo #café #2024goals", []Entity{{Start: 6, End: 11, Text: "café"}, {Start: 12, End: 22, Text: "2024goals"}}},
+	}
+	for _, c := range cases {
+		found := Hashtags(c.body)
+		if !reflect.DeepEqual(found, c.expected) {
+			t.Errorf("Hashtags(%q) = %v, expected %v", c.body, found, c.expected)
+		}
+	}
+}
+
+func TestMentions(t *testing.T) {
+	cases := []struct {
+		body     string
+		expected []Entity
+	}{
+		{"hi @Ann_B!", []Entity{{Start: 3, End: 9, Text: "ann_b"}}},
+		{"mail me at bob@example.com", []Entity{}},
+		{"@al is too short, @waytoolonghandle_x too long", []Entity{}},
+		{"@bob and @carol", []Entity{{Start: 0, End: 4, Text: "bob"}, {Start: 9, End: 15, Text: "carol"}}},
+	}
+	for _, c := range cases {
+		found := Mentions(c.body)
+		if !reflect.DeepEqual(found, c.expected) {
+			t.Errorf("Mentions(%q) = %v, expected %v", c.body, found, c.expected)
+		}
+	}
+}
+
+func TestUnique(t *testing.T) {
+	found := Hashtags("#go #Go #rust #GO")
+	unique := Unique(found)
+	if !reflect.DeepEqual(unique, []string{"go", "rust"}) {
+		t.Errorf("Recieved %v, expected [go rust]", unique)
+	}
+}
+
+func TestURLs(t *testing.T) {
+	found := URLs("read https://example.com/a?b=1. (see http://x.io) https:// nothttp://a.b")
+	if len(found) != 2 {
+		t.Fatalf("Recieved %v, expected 2 urls", found)
+	}
+	if found[0].Text != "https://example.com/a?b=1" || found[0].Start != 5 || found[0].End != 30 {
+		t.Errorf("Recieved %+v, expected the first link without the full stop", found[0])
+	}
+	if found[1].Text != "http://x.io" {
+		t.Errorf("Recieved %q, expected http://x.io", found[1].Text)
+	}
+}
@@ -0,0 +1,240 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/leiper-mike/chirpy/internal/entities"
+)
+
+// Kinds of rule.
+const (
+	Word    = "word"
+	Phrase  = "phrase"
+	Hashtag = "hashtag"
+	Regex   = "regex"
+)
+
+// Actions a matching rule can take. Hide beats Warn when both match.
+const (
+	Hide = "hide"
+	Warn = "warn"
+)
+
+const (
+	maxPatternLength = 100
+	maxRegexLength   = 200
+)
+
+type Rule struct {
+	Kind    string
+	Pattern string
+	Action  string
+}
+
+// Normalize validates a rule and puts it in the form Filter matches against:
+// words, phrases and hashtags are lowercased and trimmed, and a missing
+// action means Hide.
+func Normalize(rule Rule) (Rule, error) {
+	if rule.Action == "" {
+		rule.Action = Hide
+	}
+	if rule.Action != Hide && rule.Action != Warn {
+		return rule, errors.New("action must be hide or warn")
+	}
+	switch rule.Kind {
+	case Word, Phrase:
+		tokens := tokenize(rule.Pattern)
+		if len(tokens) == 0 {
+			return rule, errors.New("pattern must contain a word")
+		}
+		if rule.Kind == Word && len(tokens) > 1 {
+			return rule, errors.New("a word pattern must be a single word; use a phrase instead")
+		}
+		rule.Pattern = strings.Join(tokens, " ")
+	case Hashtag:
+		tag := entities.NormalizeTag(rule.Pattern)
+		found := entities.Hashtags("#" + tag)
+		if len(found) != 1 || found[0].Text != tag {
+			return rule, errors.New("pattern must be a hashtag")
+		}
+		rule.Pattern = tag
+	case Regex:
+		if rule.Pattern == "" || len(rule.Pattern) > maxRegexLength {
+			return rule, fmt.Errorf("a regex must be 1-%d characters", maxRegexLength)
+		}
+		_, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return rule, errors.New("pattern is not a valid regex")
+		}
+		return rule, nil
+	default:
+		return rule, errors.New("kind must be word, phrase, hashtag or regex")
+	}
+	if len(rule.Pattern) > maxPatternLength {
+		return rule, fmt.Errorf("pattern must be no longer than %d characters", maxPatternLength)
+	}
+	return rule, nil
+}
+
+// Filter matches texts against a user's rules. It is built once per request
+// and then costs a single pass over each text: words and hashtags are map
+// lookups per token, phrases are only tried where their first word occurs,
+// and every regex with the same action is folded into one RE2 expression,
+// which runs in time linear in the text.
+type Filter struct {
+	rules    []Rule
+	words    map[string]int
+	hashtags map[string]int
+	phrases  map[string][]phrase
+	regexes  map[string]*regexSet
+}
+
+type phrase struct {
+	tokens []string
+	rule   int
+}
+
+// regexSet is one alternation of several rules' regexes. groups[i] is the
+// submatch index that captures rule rules[i].
+type regexSet struct {
+	re     *regexp.Regexp
+	rules  []int
+	groups []int
+	next   int
+}
+
+// New builds a Filter from rules that have been through Normalize.
+func New(rules []Rule) (*Filter, error) {
+	f := &Filter{rules: rules, words: map[string]int{}, hashtags: map[string]int{}, phrases: map[string][]phrase{}, regexes: map[string]*regexSet{}}
+	patterns := map[string][]string{}
+	for i, rule := range rules {
+		switch rule.Kind {
+		case Word:
+			f.add(f.words, rule.Pattern, i)
+		case Hashtag:
+			f.add(f.hashtags, rule.Pattern, i)
+		case Phrase:
+			tokens := strings.Split(rule.Pattern, " ")
+			f.phrases[tokens[0]] = append(f.phrases[tokens[0]], phrase{tokens: tokens, rule: i})
+		case Regex:
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, err
+			}
+			set := f.regexes[rule.Action]
+			if set == nil {
+				set = &regexSet{next: 1}
+				f.regexes[rule.Action] = set
+			}
+			// the rule's own groups are numbered after the one wrapping it
+			set.rules = append(set.rules, i)
+			set.groups = append(set.groups, set.next)
+			set.next += 1 + re.NumSubexp()
+			patterns[rule.Action] = append(patterns[rule.Action], "("+re.String()+")")
+		default:
+			return nil, fmt.Errorf("unknown rule kind %q", rule.Kind)
+		}
+	}
+	for action, set := range f.regexes {
+		re, err := regexp.Compile("(?i)" + strings.Join(patterns[action], "|"))
+		if err != nil {
+			return nil, err
+		}
+		set.re = re
+	}
+	return f, nil
+}
+
+// add keeps the strongest rule when several share a pattern.
+func (f *Filter) add(m map[string]int, pattern string, rule int) {
+	if existing, ok := m[pattern]; !ok || f.rules[existing].Action == Warn {
+		m[pattern] = rule
+	}
+}
+
+// Empty reports whether the filter has no rules, letting callers skip it.
+func (f *Filter) Empty() bool {
+	return f == nil || len(f.rules) == 0
+}
+
+// Match returns the rule that applies to text, preferring one that hides it,
+// or false if none match.
+func (f *Filter) Match(text string) (Rule, bool) {
+	if f.Empty() {
+		return Rule{}, false
+	}
+	best := -1
+	consider := func(rule int) bool {
+		if best == -1 || f.rules[best].Action == Warn {
+			best = rule
+		}
+		return f.rules[best].Action == Hide
+	}
+	tokens := tokenize(text)
+	for i, token := range tokens {
+		if rule, ok := f.words[token]; ok && consider(rule) {
+			return f.rules[best], true
+		}
+		for _, p := range f.phrases[token] {
+			if hasPrefix(tokens[i:], p.tokens) && consider(p.rule) {
+				return f.rules[best], true
+			}
+		}
+	}
+	if len(f.hashtags) > 0 {
+		for _, tag := range entities.Hashtags(text) {
+			if rule, ok := f.hashtags[tag.Text]; ok && consider(rule) {
+				return f.rules[best], true
+			}
+		}
+	}
+	if set := f.regexes[Hide]; set != nil {
+		if loc := set.re.FindStringSubmatchIndex(text); loc != nil {
+			consider(set.matched(loc))
+			return f.rules[best], true
+		}
+	}
+	if set := f.regexes[Warn]; set != nil && best == -1 {
+		if loc := set.re.FindStringSubmatchIndex(text); loc != nil {
+			consider(set.matched(loc))
+		}
+	}
+	if best == -1 {
+		return Rule{}, false
+	}
+	return f.rules[best], true
+}
+
+// matched finds which rule's group took part in a match.
+func (s *regexSet) matched(loc []int) int {
+	for i, group := range s.groups {
+		if loc[2*group] >= 0 {
+			return s.rules[i]
+		}
+	}
+	return s.rules[0]
+}
+
+func hasPrefix(tokens, prefix []string) bool {
+	if len(tokens) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if tokens[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenize splits text into lowercased runs of letters, digits, underscores
+// and apostrophes, so "Don't" stays one word and "spoiler!" matches "spoiler".
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return r != '_' && r != '\'' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
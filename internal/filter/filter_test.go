@@ -0,0 +1,112 @@
+package filter
+
+import "testing"
+
+func mustFilter(t *testing.T, rules ...Rule) *Filter {
+	t.Helper()
+	normalized := []Rule{}
+	for _, rule := range rules {
+		n, err := Normalize(rule)
+		if err != nil {
+			t.Fatalf("Normalize(%v) failed: %v", rule, err)
+		}
+		normalized = append(normalized, n)
+	}
+	f, err := New(normalized)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return f
+}
+
+func TestNormalize(t *testing.T) {
+	cases := []struct {
+		rule    Rule
+		pattern string
+		wantErr bool
+	}{
+		{Rule{Kind: Word, Pattern: "  Spoiler "}, "spoiler", false},
+		{Rule{Kind: Word, Pattern: "two words"}, "", true},
+		{Rule{Kind: Phrase, Pattern: "Season  Finale!"}, "season finale", false},
+		{Rule{Kind: Phrase, Pattern: "!!!"}, "", true},
+		{Rule{Kind: Hashtag, Pattern: "#GoT"}, "got", false},
+		{Rule{Kind: Hashtag, Pattern: "#1"}, "", true},
+		{Rule{Kind: Regex, Pattern: "sp(o|0)iler"}, "sp(o|0)iler", false},
+		{Rule{Kind: Regex, Pattern: "("}, "", true},
+		{Rule{Kind: "emoji", Pattern: "x"}, "", true},
+		{Rule{Kind: Word, Pattern: "x", Action: "delete"}, "", true},
+	}
+	for _, c := range cases {
+		got, err := Normalize(c.rule)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("Normalize(%v) = %v, expected an error", c.rule, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Normalize(%v) failed: %v", c.rule, err)
+			continue
+		}
+		if got.Pattern != c.pattern || got.Action != Hide {
+			t.Errorf("Recieved %v from Normalize(%v), expected pattern %q with action hide", got, c.rule, c.pattern)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	f := mustFilter(t,
+		Rule{Kind: Word, Pattern: "spoiler", Action: Warn},
+		Rule{Kind: Phrase, Pattern: "red wedding"},
+		Rule{Kind: Hashtag, Pattern: "crypto"},
+		Rule{Kind: Regex, Pattern: `\bbuy (now|today)\b`, Action: Warn},
+		Rule{Kind: Regex, Pattern: `(\d+)x gains`},
+	)
+	cases := []struct {
+		text    string
+		pattern string
+	}{
+		{"No spoilers here", ""},
+		{"SPOILER: it was the butler", "spoiler"},
+		{"the Red  Wedding episode", "red wedding"},
+		{"a wedding in red", ""},
+		{"going to the moon #Crypto", "crypto"},
+		{"crypto without the sigil", ""},
+		{"Buy now while stocks last", `\bbuy (now|today)\b`},
+		{"buy today, 100x gains", `(\d+)x gains`},
+		{"spoiler: red wedding", "red wedding"},
+	}
+	for _, c := range cases {
+		rule, ok := f.Match(c.text)
+		if c.pattern == "" {
+			if ok {
+				t.Errorf("Recieved %v for %q, expected no match", rule, c.text)
+			}
+			continue
+		}
+		if !ok || rule.Pattern != c.pattern {
+			t.Errorf("Recieved %v, %v for %q, expected %q", rule, ok, c.text, c.pattern)
+		}
+	}
+}
+
+func TestMatchPrefersHide(t *testing.T) {
+	f := mustFilter(t,
+		Rule{Kind: Word, Pattern: "finale", Action: Warn},
+		Rule{Kind: Word, Pattern: "finale"},
+	)
+	rule, ok := f.Match("the finale")
+	if !ok || rule.Action != Hide {
+		t.Errorf("Recieved %v, expected the hide rule to win", rule)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	var f *Filter
+	if !f.Empty() {
+		t.Error("A nil filter should be empty")
+	}
+	if _, ok := f.Match("anything"); ok {
+		t.Error("A nil filter should not match")
+	}
+}
@@ -0,0 +1,134 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported image format")
+var ErrTooLarge = errors.New("image dimensions too large")
+
+// Sniff reports the content type of data based on its magic bytes, ignoring
+// whatever the client claimed in the upload.
+func Sniff(data []byte) string {
+	return http.DetectContentType(data)
+}
+
+// Decode validates and decodes a png, jpeg or gif. The header is checked
+// before the full decode so huge images are rejected without allocating them.
+func Decode(data []byte, maxPixels int) (image.Image, string, error) {
+	var decodeConfig func(io.Reader) (image.Config, error)
+	var decode func(io.Reader) (image.Image, error)
+	contentType := Sniff(data)
+	switch contentType {
+	case "image/png":
+		decodeConfig, decode = png.DecodeConfig, png.Decode
+	case "image/jpeg":
+		decodeConfig, decode = jpeg.DecodeConfig, jpeg.Decode
+	case "image/gif":
+		decodeConfig, decode = gif.DecodeConfig, gif.Decode
+	default:
+		return nil, contentType, ErrUnsupportedFormat
+	}
+	conf, err := decodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, contentType, err
+	}
+	if conf.Width <= 0 || conf.Height <= 0 || conf.Width*conf.Height > maxPixels {
+		return nil, contentType, ErrTooLarge
+	}
+	img, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, contentType, err
+	}
+	return img, contentType, nil
+}
+
+// Square center crops img to a square and scales it to size x size.
+func Square(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+// EncodeJPEG re-encodes img. Re-encoding from decoded pixels drops any
+// metadata (EXIF, comments) present in the original upload.
+func EncodeJPEG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Fit scales img down so neither side exceeds maxSize, keeping its aspect
+// ratio. Images that already fit are returned unchanged.
+func Fit(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	if b.Dx() <= maxSize && b.Dy() <= maxSize {
+		return img
+	}
+	w, h := maxSize, b.Dy()*maxSize/b.Dx()
+	if b.Dy() > b.Dx() {
+		w, h = b.Dx()*maxSize/b.Dy(), maxSize
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// EncodePNG re-encodes img, dropping any metadata chunks of the original.
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeGIF decodes every frame of a possibly animated gif, rejecting ones
+// whose total pixel count across frames exceeds maxPixels.
+func DecodeGIF(data []byte, maxPixels int) (*gif.GIF, error) {
+	if Sniff(data) != "image/gif" {
+		return nil, ErrUnsupportedFormat
+	}
+	conf, err := gif.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if conf.Width <= 0 || conf.Height <= 0 || conf.Width*conf.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(g.Image)*conf.Width*conf.Height > maxPixels {
+		return nil, ErrTooLarge
+	}
+	return g, nil
+}
+
+// EncodeGIF re-encodes an animation, keeping frames, timing and loop count
+// but dropping comment and application extensions.
+func EncodeGIF(g *gif.GIF) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{Image: g.Image, Delay: g.Delay, LoopCount: g.LoopCount, Disposal: g.Disposal, Config: g.Config, BackgroundIndex: g.BackgroundIndex})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"testing"
+)
+
+func testPNG(t *testing.T, w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	img, contentType, err := Decode(testPNG(t, 300, 200), 1000*1000)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	if contentType != "image/png" {
+		t.Errorf("Recieved content type %v, expected image/png", contentType)
+	}
+	if img.Bounds().Dx() != 300 {
+		t.Errorf("Recieved width %v, expected 300", img.Bounds().Dx())
+	}
+	_, _, err = Decode(testPNG(t, 300, 200), 100)
+	if err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge, recieved %v", err)
+	}
+	_, _, err = Decode([]byte("definitely not an image"), 1000)
+	if err != ErrUnsupportedFormat {
+		t.Errorf("Expected ErrUnsupportedFormat, recieved %v", err)
+	}
+}
+
+func TestSquare(t *testing.T) {
+	img, _, err := Decode(testPNG(t, 300, 200), 1000*1000)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	thumb := Square(img, 48)
+	if thumb.Bounds().Dx() != 48 || thumb.Bounds().Dy() != 48 {
+		t.Errorf("Recieved %v, expected 48x48", thumb.Bounds())
+	}
+}
+
+func TestFit(t *testing.T) {
+	img, _, err := Decode(testPNG(t, 300, 200), 1000*1000)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	fitted := Fit(img, 150)
+	if fitted.Bounds().Dx() != 150 || fitted.Bounds().Dy() != 100 {
+		t.Errorf("Recieved %v, expected 150x100", fitted.Bounds())
+	}
+	if Fit(img, 400) != img {
+		t.Error("Fit should leave images that already fit alone")
+	}
+}
+
+func TestGIFRoundTrip(t *testing.T) {
+	palette := color.Palette{color.Black, color.White}
+	frames := []*image.Paletted{image.NewPaletted(image.Rect(0, 0, 10, 10), palette), image.NewPaletted(image.Rect(0, 0, 10, 10), palette)}
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{Image: frames, Delay: []int{10, 10}})
+	if err != nil {
+		t.Fatalf("Failed to encode gif: %v", err)
+	}
+	g, err := DecodeGIF(buf.Bytes(), 1000)
+	if err != nil {
+		t.Fatalf("Failed to decode gif: %v", err)
+	}
+	if len(g.Image) != 2 {
+		t.Errorf("Recieved %d frames, expected 2", len(g.Image))
+	}
+	if _, err := EncodeGIF(g); err != nil {
+		t.Errorf("Failed to re-encode gif: %v", err)
+	}
+	if _, err := DecodeGIF(buf.Bytes(), 150); err != ErrTooLarge {
+		t.Errorf("Expected ErrTooLarge for total frame pixels, recieved %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// withOrientation splices an EXIF segment with the given orientation into a jpeg.
+func withOrientation(t *testing.T, orientation uint16) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 2)), nil); err != nil {
+		t.Fatalf("Failed to encode jpeg: %v", err)
+	}
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08\x00\x01")
+	entry := make([]byte, 12)
+	binary.BigEndian.PutUint16(entry, exifOrientationTag)
+	binary.BigEndian.PutUint16(entry[2:], 3)
+	binary.BigEndian.PutUint32(entry[4:], 1)
+	binary.BigEndian.PutUint16(entry[8:], orientation)
+	segment := append([]byte("Exif\x00\x00"), append(tiff, entry...)...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	data := append([]byte{}, buf.Bytes()[:2]...)
+	data = append(data, app1...)
+	data = append(data, segment...)
+	return append(data, buf.Bytes()[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	if o := Orientation(withOrientation(t, 6)); o != 6 {
+		t.Errorf("Recieved orientation %d, expected 6", o)
+	}
+	if o := Orientation(testPNG(t, 2, 2)); o != 1 {
+		t.Errorf("Recieved orientation %d for a png, expected 1", o)
+	}
+	if _, _, err := Decode(withOrientation(t, 6), 100); err != nil {
+		t.Errorf("Failed to decode jpeg with EXIF: %v", err)
+	}
+}
+
+func TestOrient(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 3, 2))
+	red := color.RGBA{R: 255, A: 255}
+	img.SetRGBA(0, 0, red)
+	rotated := Orient(img, 6)
+	if rotated.Bounds().Dx() != 2 || rotated.Bounds().Dy() != 3 {
+		t.Fatalf("Recieved %v, expected 2x3", rotated.Bounds())
+	}
+	// rotating 90 degrees clockwise moves the top left pixel to the top right
+	if rotated.At(1, 0) != red {
+		t.Errorf("Recieved %v at top right, expected red", rotated.At(1, 0))
+	}
+	if Orient(img, 1) != img {
+		t.Error("Orientation 1 should leave the image alone")
+	}
+}
@@ -0,0 +1,47 @@
+// Package polls checks the polls people attach to chirps.
+package polls
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	MinOptions      = 2
+	MaxOptions      = 4
+	MaxOptionLength = 25
+	MinDuration     = 5 * time.Minute
+	MaxDuration     = 7 * 24 * time.Hour
+	DefaultDuration = 24 * time.Hour
+)
+
+// Validate trims options in place and checks them and the duration, given in
+// minutes with 0 meaning DefaultDuration. It returns how long the poll runs.
+func Validate(options []string, durationMinutes int) (time.Duration, error) {
+	if len(options) < MinOptions || len(options) > MaxOptions {
+		return 0, fmt.Errorf("A poll must have %d to %d options", MinOptions, MaxOptions)
+	}
+	seen := map[string]bool{}
+	for i, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > MaxOptionLength {
+			return 0, fmt.Errorf("Poll options must be 1 to %d characters", MaxOptionLength)
+		}
+		if seen[strings.ToLower(option)] {
+			return 0, errors.New("Poll options must be different")
+		}
+		seen[strings.ToLower(option)] = true
+		options[i] = option
+	}
+	if durationMinutes == 0 {
+		return DefaultDuration, nil
+	}
+	// checked before converting, as a large count would overflow the duration
+	if durationMinutes < int(MinDuration.Minutes()) || durationMinutes > int(MaxDuration.Minutes()) {
+		return 0, errors.New("A poll must run for between 5 minutes and 7 days")
+	}
+	return time.Duration(durationMinutes) * time.Minute, nil
+}
@@ -0,0 +1,52 @@
+package polls
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		options  []string
+		minutes  int
+		expected time.Duration
+		trimmed  []string
+	}{
+		{[]string{" Yes ", "No"}, 0, DefaultDuration, []string{"Yes", "No"}},
+		{[]string{"a", "b", "c", "d"}, 5, 5 * time.Minute, []string{"a", "b", "c", "d"}},
+		{[]string{"a", "b"}, 7 * 24 * 60, MaxDuration, []string{"a", "b"}},
+	}
+	for _, c := range cases {
+		duration, err := Validate(c.options, c.minutes)
+		if err != nil {
+			t.Errorf("Validate(%q, %d) failed: %v", c.options, c.minutes, err)
+			continue
+		}
+		if duration != c.expected || !reflect.DeepEqual(c.options, c.trimmed) {
+			t.Errorf("Recieved %v and options %q, expected %v and %q", duration, c.options, c.expected, c.trimmed)
+		}
+	}
+	invalid := []struct {
+		options []string
+		minutes int
+	}{
+		{[]string{"only"}, 0},
+		{[]string{"a", "b", "c", "d", "e"}, 0},
+		{[]string{"a", "  "}, 0},
+		{[]string{"a", "this option is far too long to fit"}, 0},
+		{[]string{"Same", "same"}, 0},
+		{[]string{"a", "b"}, 4},
+		{[]string{"a", "b"}, -60},
+		{[]string{"a", "b"}, 7*24*60 + 1},
+		// multiplied out as a duration this wraps around to about an hour
+		{[]string{"a", "b"}, 307445794},
+		{[]string{"a", "b"}, math.MaxInt},
+	}
+	for _, c := range invalid {
+		if duration, err := Validate(c.options, c.minutes); err == nil {
+			t.Errorf("Validate(%q, %d) = %v, expected an error", c.options, c.minutes, duration)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Proxies are the reverse proxies whose X-Forwarded-For headers are trusted.
+type Proxies []netip.Prefix
+
+// ParseProxies reads a comma separated list of addresses and CIDR ranges.
+func ParseProxies(s string) (Proxies, error) {
+	proxies := Proxies{}
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(field)
+			if addrErr != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", field)
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+func (p Proxies) trusted(addr netip.Addr) bool {
+	for _, prefix := range p {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that made r. Anyone can send
+// an X-Forwarded-For header, so it is only believed as far as trusted
+// proxies wrote it: walking back from the connection's peer, the first
+// address that isn't a trusted proxy is the client.
+func (p Proxies) ClientIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	client, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}
+	}
+	client = client.Unmap()
+	if !p.trusted(client) {
+		return client
+	}
+	hops := []string{}
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			// nothing further along can be trusted
+			break
+		}
+		client = addr.Unmap()
+		if !p.trusted(client) {
+			break
+		}
+	}
+	return client
+}
+
+// IPKey is the bucket key for a client address. IPv6 clients are commonly
+// handed a whole /64, so they are limited by it rather than by address.
+func IPKey(addr netip.Addr) string {
+	if addr.Is6() {
+		prefix, _ := addr.Prefix(64)
+		return prefix.String()
+	}
+	return addr.String()
+}
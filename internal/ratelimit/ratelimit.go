@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Policy is a token bucket holding up to Limit tokens, refilled at Limit
+// tokens per Period. Each request spends one.
+type Policy struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Bucket is the stored state of one key's bucket. The zero Bucket is full.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// until the bucket is full again
+	Reset time.Duration
+	// until the next request would be allowed; zero when this one was
+	RetryAfter time.Duration
+}
+
+// Take spends a token from b at now if there is one, returning the bucket's
+// new state and the outcome.
+func (p Policy) Take(b Bucket, now time.Time) (Bucket, Result) {
+	rate := float64(p.Limit) / p.Period.Seconds()
+	tokens := float64(p.Limit)
+	if !b.Updated.IsZero() {
+		elapsed := max(now.Sub(b.Updated).Seconds(), 0)
+		tokens = min(b.Tokens+elapsed*rate, float64(p.Limit))
+	}
+	res := Result{Limit: p.Limit}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+	res.Remaining = int(math.Floor(tokens))
+	res.Reset = seconds((float64(p.Limit) - tokens) / rate)
+	return Bucket{Tokens: tokens, Updated: now}, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// SetHeaders describes the outcome of a request to the client, with the
+// RateLimit fields from the IETF draft and Retry-After when it was refused.
+// Times are rounded up to whole seconds.
+func (res Result) SetHeaders(h http.Header, p Policy) {
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("RateLimit-Policy", strconv.Itoa(p.Limit)+";w="+strconv.Itoa(ceilSeconds(p.Period)))
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(max(ceilSeconds(res.RetryAfter), 1)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// Store keeps buckets and spends tokens from them atomically.
+type Store interface {
+	Take(ctx context.Context, key string, p Policy) (Result, error)
+}
+
+// MemoryStore keeps buckets in process, so each instance of the server
+// limits on its own.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	period time.Duration
+}
+
+// how often idle buckets are dropped
+const sweepInterval = time.Minute
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]memoryBucket{}, now: time.Now}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, p Policy) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+	b, res := p.Take(s.buckets[key].Bucket, now)
+	s.buckets[key] = memoryBucket{Bucket: b, period: p.Period}
+	return res, nil
+}
+
+// sweep drops buckets that have been idle long enough to be full again,
+// which is the same as not having one.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.Updated) >= b.period {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// Len is the number of buckets being kept.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
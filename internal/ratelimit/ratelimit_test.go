@@ -0,0 +1,122 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	p := Policy{Limit: 3, Period: 3 * time.Second}
+	now := time.Now()
+	b := Bucket{}
+	var res Result
+	for i := 0; i < 3; i++ {
+		b, res = p.Take(b, now)
+		if !res.Allowed || res.Remaining != 2-i {
+			t.Fatalf("Recieved %+v for request %d, expected it allowed with %d remaining", res, i+1, 2-i)
+		}
+	}
+	b, res = p.Take(b, now)
+	if res.Allowed || res.RetryAfter != time.Second || res.Reset != 3*time.Second {
+		t.Errorf("Recieved %+v for an empty bucket, expected a refusal retrying after 1s and full after 3s", res)
+	}
+	b, res = p.Take(b, now.Add(1500*time.Millisecond))
+	if !res.Allowed || res.Remaining != 0 {
+		t.Errorf("Recieved %+v after refilling 1.5 tokens, expected it allowed with 0 remaining", res)
+	}
+	_, res = p.Take(b, now.Add(time.Hour))
+	if !res.Allowed || res.Remaining != 2 {
+		t.Errorf("Recieved %+v after a long wait, expected a full bucket", res)
+	}
+}
+
+func TestSetHeaders(t *testing.T) {
+	p := Policy{Limit: 10, Period: time.Minute}
+	h := http.Header{}
+	Result{Allowed: false, Limit: 10, Remaining: 0, Reset: 59500 * time.Millisecond, RetryAfter: 5100 * time.Millisecond}.SetHeaders(h, p)
+	want := map[string]string{"RateLimit-Limit": "10", "RateLimit-Remaining": "0", "RateLimit-Reset": "60", "RateLimit-Policy": "10;w=60", "Retry-After": "6"}
+	for name, value := range want {
+		if h.Get(name) != value {
+			t.Errorf("Recieved %s: %q, expected %q", name, h.Get(name), value)
+		}
+	}
+	h = http.Header{}
+	Result{Allowed: true, Limit: 10, Remaining: 9}.SetHeaders(h, p)
+	if h.Get("Retry-After") != "" {
+		t.Error("Retry-After should only be set on refusals")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := NewMemoryStore()
+	now := time.Now()
+	s.now = func() time.Time { return now }
+	p := Policy{Limit: 1, Period: time.Minute}
+	ctx := context.Background()
+	res, _ := s.Take(ctx, "a", p)
+	if !res.Allowed {
+		t.Error("First request for a key should be allowed")
+	}
+	res, _ = s.Take(ctx, "a", p)
+	if res.Allowed {
+		t.Error("Second request for a key should be refused")
+	}
+	res, _ = s.Take(ctx, "b", p)
+	if !res.Allowed {
+		t.Error("Keys should have their own buckets")
+	}
+	now = now.Add(2 * time.Minute)
+	s.Take(ctx, "c", p)
+	if s.Len() != 1 {
+		t.Errorf("Recieved %d buckets, expected idle ones to be swept", s.Len())
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseProxies("10.0.0.0/8, 192.168.1.1")
+	if err != nil {
+		t.Fatalf("ParseProxies failed: %v", err)
+	}
+	cases := []struct {
+		remote string
+		xff    string
+		want   string
+	}{
+		{"203.0.113.7:5000", "", "203.0.113.7"},
+		// only trusted proxies get to say who the client is
+		{"203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"10.1.2.3:5000", "198.51.100.1", "198.51.100.1"},
+		// a client can prepend anything, but not past the proxies
+		{"10.1.2.3:5000", "1.1.1.1, 198.51.100.1, 192.168.1.1", "198.51.100.1"},
+		{"10.1.2.3:5000", "garbage, 10.9.9.9", "10.9.9.9"},
+		{"[2001:db8::1]:5000", "", "2001:db8::1"},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = c.remote
+		if c.xff != "" {
+			r.Header.Set("X-Forwarded-For", c.xff)
+		}
+		if got := proxies.ClientIP(r).String(); got != c.want {
+			t.Errorf("Recieved %s for %s via %q, expected %s", got, c.remote, c.xff, c.want)
+		}
+	}
+	if _, err := ParseProxies("10.0.0.0/8, nonsense"); err == nil {
+		t.Error("ParseProxies should reject invalid entries")
+	}
+}
+
+func TestIPKey(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	r.RemoteAddr = "[2001:db8:1:2:3:4:5:6]:443"
+	if got := IPKey(Proxies{}.ClientIP(r)); got != "2001:db8:1:2::/64" {
+		t.Errorf("Recieved %s, expected the /64", got)
+	}
+	r.RemoteAddr = "203.0.113.7:443"
+	if got := IPKey(Proxies{}.ClientIP(r)); got != "203.0.113.7" {
+		t.Errorf("Recieved %s, expected the address", got)
+	}
+}
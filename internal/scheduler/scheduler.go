@@ -0,0 +1,42 @@
+// Package scheduler works through jobs as they come due, setting aside those
+// that fail so they can't hold up the rest.
+package scheduler
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Queue hands out due jobs.
+type Queue interface {
+	// RunNext claims the next due job that isn't backing off and runs it,
+	// returning its ID even when running it fails. ok is false when no job
+	// was claimed, and err is then a failure to claim one.
+	RunNext(ctx context.Context) (id uuid.UUID, ok bool, err error)
+	// Postpone backs off a job that failed, so it is claimed after the
+	// others that are due.
+	Postpone(ctx context.Context, id uuid.UUID) error
+}
+
+// Drain runs due jobs until none are left. A job that fails is passed to
+// failed and postponed, and the jobs after it still run. Drain stops at the
+// first error it can't set aside that way.
+func Drain(ctx context.Context, q Queue, failed func(id uuid.UUID, err error)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id, ok, err := q.RunNext(ctx)
+		if !ok {
+			return err
+		}
+		if err != nil {
+			failed(id, err)
+			err = q.Postpone(ctx, id)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// fakeQueue runs its jobs in order, skipping those that are done or postponed.
+type fakeQueue struct {
+	jobs        []uuid.UUID
+	failing     map[uuid.UUID]bool
+	claimErr    error
+	postponeErr error
+	ran         []uuid.UUID
+	postponed   []uuid.UUID
+}
+
+func (q *fakeQueue) RunNext(ctx context.Context) (uuid.UUID, bool, error) {
+	if q.claimErr != nil {
+		return uuid.Nil, false, q.claimErr
+	}
+	for _, id := range q.jobs {
+		if contains(q.ran, id) || contains(q.postponed, id) {
+			continue
+		}
+		if q.failing[id] {
+			return id, true, errors.New("publish failed")
+		}
+		q.ran = append(q.ran, id)
+		return id, true, nil
+	}
+	return uuid.Nil, false, nil
+}
+
+func (q *fakeQueue) Postpone(ctx context.Context, id uuid.UUID) error {
+	if q.postponeErr != nil {
+		return q.postponeErr
+	}
+	q.postponed = append(q.postponed, id)
+	return nil
+}
+
+func contains(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, other := range ids {
+		if other == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDrain(t *testing.T) {
+	bad, next, last := uuid.New(), uuid.New(), uuid.New()
+	q := &fakeQueue{jobs: []uuid.UUID{bad, next, last}, failing: map[uuid.UUID]bool{bad: true}}
+	failed := []uuid.UUID{}
+	err := Drain(context.Background(), q, func(id uuid.UUID, err error) {
+		failed = append(failed, id)
+	})
+	if err != nil {
+		t.Fatalf("Drain failed: %v", err)
+	}
+	if !reflect.DeepEqual(q.ran, []uuid.UUID{next, last}) {
+		t.Errorf("Recieved %v run, expected the jobs after the failing one %v", q.ran, []uuid.UUID{next, last})
+	}
+	if !reflect.DeepEqual(q.postponed, []uuid.UUID{bad}) || !reflect.DeepEqual(failed, []uuid.UUID{bad}) {
+		t.Errorf("Recieved %v postponed and %v reported, expected only %v", q.postponed, failed, bad)
+	}
+}
+
+func TestDrainStops(t *testing.T) {
+	claimErr := errors.New("connection refused")
+	q := &fakeQueue{jobs: []uuid.UUID{uuid.New()}, claimErr: claimErr}
+	err := Drain(context.Background(), q, func(uuid.UUID, error) {})
+	if err != claimErr {
+		t.Errorf("Recieved %v, expected the claim error", err)
+	}
+	// a job that can't be set aside would otherwise be claimed again forever
+	postponeErr := errors.New("connection reset")
+	q = &fakeQueue{jobs: []uuid.UUID{uuid.New(), uuid.New()}, failing: map[uuid.UUID]bool{}, postponeErr: postponeErr}
+	q.failing[q.jobs[0]] = true
+	err = Drain(context.Background(), q, func(uuid.UUID, error) {})
+	if err != postponeErr || len(q.ran) != 0 {
+		t.Errorf("Recieved %v after running %v, expected the postpone error and nothing run", err, q.ran)
+	}
+}
@@ -0,0 +1,181 @@
+// Package search parses chirp search queries and the highlights of their
+// matches.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/leiper-mike/chirpy/internal/entities"
+)
+
+// Query is a parsed search. Text is what's left once the operators are taken
+// out, in the syntax of Postgres' websearch_to_tsquery: words, "quoted
+// phrases", OR and -excluded words.
+type Query struct {
+	Text string
+	// handle without the @, empty for anyone
+	From string
+	// every one of them must be in the chirp
+	Tags []string
+	// Since is inclusive and Until exclusive; zero when unbounded
+	Since time.Time
+	Until time.Time
+}
+
+// Parse reads a query such as
+//
+//	"release notes" #golang from:@chirpy since:2024-01-01 until:2024-06-30
+//
+// Dates are days in UTC or RFC 3339 times. An until: day includes the whole
+// day.
+func Parse(s string) (Query, error) {
+	q := Query{Tags: []string{}}
+	text := []string{}
+	for _, token := range tokenize(s) {
+		name, value, isOperator := strings.Cut(token, ":")
+		if strings.HasPrefix(token, "\"") || strings.HasPrefix(token, "-\"") {
+			isOperator = false
+		}
+		switch {
+		case isOperator && strings.EqualFold(name, "from"):
+			handle := strings.ToLower(strings.TrimPrefix(value, "@"))
+			if handle == "" {
+				return Query{}, errors.New("from: needs a handle")
+			}
+			if q.From != "" && q.From != handle {
+				return Query{}, errors.New("Only one from: is allowed")
+			}
+			q.From = handle
+		case isOperator && strings.EqualFold(name, "since"):
+			t, err := parseDate(value, false)
+			if err != nil {
+				return Query{}, err
+			}
+			q.Since = t
+		case isOperator && strings.EqualFold(name, "until"):
+			t, err := parseDate(value, true)
+			if err != nil {
+				return Query{}, err
+			}
+			q.Until = t
+		case strings.HasPrefix(token, "#") && len(token) > 1:
+			tag := entities.NormalizeTag(token)
+			if !contains(q.Tags, tag) {
+				q.Tags = append(q.Tags, tag)
+			}
+		default:
+			text = append(text, token)
+		}
+	}
+	q.Text = strings.Join(text, " ")
+	if q.Text == "" && q.From == "" && len(q.Tags) == 0 {
+		return Query{}, errors.New("Empty search")
+	}
+	if !q.Since.IsZero() && !q.Until.IsZero() && !q.Since.Before(q.Until) {
+		return Query{}, errors.New("since: must be before until:")
+	}
+	return q, nil
+}
+
+// tokenize splits s on whitespace, keeping quoted phrases whole. An
+// unterminated quote runs to the end.
+func tokenize(s string) []string {
+	tokens := []string{}
+	var current strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		token := current.String()
+		if quoted {
+			token += "\""
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+func parseDate(s string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		if end {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("Invalid date %q, expected YYYY-MM-DD", s)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Range is a highlighted span, in characters (runes), end exclusive.
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// StartSel and StopSel mark matches in the text given to Highlights. They
+// are control characters so they can't be confused with a chirp's own text.
+const (
+	StartSel = "\x02"
+	StopSel  = "\x03"
+)
+
+// Highlights returns the marked spans of marked, which is text with
+// StartSel and StopSel inserted around matches. If marked turns out not to
+// be text with markers added there are no highlights.
+func Highlights(text, marked string) []Range {
+	ranges := []Range{}
+	pos := 0
+	start := -1
+	rest := text
+	for _, r := range marked {
+		switch string(r) {
+		case StartSel:
+			start = pos
+			continue
+		case StopSel:
+			if start >= 0 && pos > start {
+				ranges = append(ranges, Range{Start: start, End: pos})
+			}
+			start = -1
+			continue
+		}
+		textRune, size := utf8.DecodeRuneInString(rest)
+		if size == 0 || textRune != r {
+			return []Range{}
+		}
+		rest = rest[size:]
+		pos++
+	}
+	if rest != "" {
+		return []Range{}
+	}
+	return ranges
+}
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	day := func(s string) time.Time {
+		d, _ := time.Parse(time.DateOnly, s)
+		return d
+	}
+	cases := []struct {
+		input    string
+		expected Query
+	}{
+		{"hello world", Query{Text: "hello world", Tags: []string{}}},
+		{`"release notes" -beta`, Query{Text: `"release notes" -beta`, Tags: []string{}}},
+		// operators inside a phrase are just words
+		{`"from:@bob said"`, Query{Text: `"from:@bob said"`, Tags: []string{}}},
+		{"#Go #golang #go tips", Query{Text: "tips", Tags: []string{"go", "golang"}}},
+		{"from:@Chirpy launch", Query{Text: "launch", From: "chirpy", Tags: []string{}}},
+		{"since:2024-01-01 until:2024-01-31 outage", Query{Text: "outage", Tags: []string{}, Since: day("2024-01-01"), Until: day("2024-02-01")}},
+		{"see https://example.com", Query{Text: "see https://example.com", Tags: []string{}}},
+		{`"unterminated phrase`, Query{Text: `"unterminated phrase"`, Tags: []string{}}},
+	}
+	for _, c := range cases {
+		q, err := Parse(c.input)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", c.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(q, c.expected) {
+			t.Errorf("Parse(%q) = %+v, expected %+v", c.input, q, c.expected)
+		}
+	}
+	invalid := []string{"", "   ", "since:2024-01-01", "from:", "from:@a from:@b x", "since:yesterday x", "since:2024-02-01 until:2024-01-01 x"}
+	for _, input := range invalid {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("Parse(%q) should fail", input)
+		}
+	}
+}
+
+func TestHighlights(t *testing.T) {
+	cases := []struct {
+		text     string
+		marked   string
+		expected []Range
+	}{
+		{"no matches", "no matches", []Range{}},
+		{"Release notes are out", "\x02Release\x03 \x02notes\x03 are out", []Range{{0, 7}, {8, 13}}},
+		{"café crème", "café \x02crème\x03", []Range{{5, 10}}},
+		// the text doesn't line up with the markup, so nothing can be trusted
+		{"one two", "\x02one\x03  two", []Range{}},
+		{"one two", "\x02one\x03", []Range{}},
+	}
+	for _, c := range cases {
+		found := Highlights(c.text, c.marked)
+		if !reflect.DeepEqual(found, c.expected) {
+			t.Errorf("Highlights(%q, %q) = %v, expected %v", c.text, c.marked, found, c.expected)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package spam
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A verification challenge is a proof of work: the client must find a
+// solution such that SHA-256(challenge + ":" + solution) starts with the
+// challenge's number of zero bits. That costs a person posting one chirp a
+// moment and a bot posting thousands a lot. Challenges are signed rather than
+// stored, and bound to a subject such as the author and chirp body so that a
+// solution can't be spent on anything else.
+
+// NewChallenge issues a challenge for subject that expires at expires.
+func NewChallenge(secret []byte, subject string, difficulty int, expires time.Time) string {
+	nonce := make([]byte, 12)
+	rand.Read(nonce)
+	payload := strings.Join([]string{hex.EncodeToString(nonce), strconv.Itoa(difficulty), strconv.FormatInt(expires.Unix(), 10)}, ".")
+	return payload + "." + sign(secret, subject, payload)
+}
+
+// VerifyChallenge checks a solution to a challenge issued for subject.
+func VerifyChallenge(secret []byte, subject, challenge, solution string, now time.Time) error {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return errors.New("malformed challenge")
+	}
+	payload := strings.Join(parts[:3], ".")
+	if !hmac.Equal([]byte(parts[3]), []byte(sign(secret, subject, payload))) {
+		return errors.New("challenge was not issued for this chirp")
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return errors.New("malformed challenge")
+	}
+	expires, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return errors.New("malformed challenge")
+	}
+	if !now.Before(time.Unix(expires, 0)) {
+		return errors.New("challenge has expired")
+	}
+	if leadingZeros(challenge, solution) < difficulty {
+		return fmt.Errorf("solution does not have %d leading zero bits", difficulty)
+	}
+	return nil
+}
+
+// Solve finds a solution to a challenge, the way a client would.
+func Solve(challenge string) (string, error) {
+	parts := strings.Split(challenge, ".")
+	if len(parts) != 4 {
+		return "", errors.New("malformed challenge")
+	}
+	difficulty, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", errors.New("malformed challenge")
+	}
+	for i := 0; ; i++ {
+		solution := strconv.Itoa(i)
+		if leadingZeros(challenge, solution) >= difficulty {
+			return solution, nil
+		}
+	}
+}
+
+func sign(secret []byte, subject, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(subject + "\n" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func leadingZeros(challenge, solution string) int {
+	sum := sha256.Sum256([]byte(challenge + ":" + solution))
+	zeros := 0
+	for _, b := range sum {
+		zeros += bits.LeadingZeros8(b)
+		if b != 0 {
+			break
+		}
+	}
+	return zeros
+}
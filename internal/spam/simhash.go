@@ -0,0 +1,49 @@
+package spam
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// Simhash fingerprints text so that texts differing in a few words get
+// fingerprints differing in a few bits. It is built from the words and
+// overlapping pairs of words, and ignores case and punctuation.
+func Simhash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	shingles := make([]string, 0, 2*len(words))
+	for i, word := range words {
+		shingles = append(shingles, word)
+		if i+1 < len(words) {
+			shingles = append(shingles, word+" "+words[i+1])
+		}
+	}
+	var weights [64]int
+	for _, shingle := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(shingle))
+		sum := h.Sum64()
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var hash uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// Distance is the number of bits two fingerprints differ in.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
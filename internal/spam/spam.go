@@ -0,0 +1,245 @@
+package spam
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Actions a verdict can take, from least to most severe.
+const (
+	Allow  = "allow"
+	Verify = "verify"
+	Hold   = "hold"
+	Reject = "reject"
+)
+
+// Input is what the pipeline knows about a chirp being posted.
+type Input struct {
+	Body       string
+	Links      int
+	AccountAge time.Duration
+	// how many chirps the author posted within Rules.Velocity.Window
+	RecentCount int
+	// bodies of the author's latest chirps, up to Rules.Duplicate.Lookback
+	Recent []string
+}
+
+// Signal is one check's contribution to a verdict.
+type Signal struct {
+	Check  string  `json:"check"`
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+type Verdict struct {
+	Action  string
+	Score   float64
+	Signals []Signal
+}
+
+// A Check scores one aspect of a chirp. It returns a zero Signal when it
+// finds nothing.
+type Check func(in Input) Signal
+
+// Duration is a time.Duration written as a string such as "24h" in JSON.
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	err := json.Unmarshal(b, &s)
+	if err != nil {
+		return err
+	}
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Rules configure the built in checks and the score each action starts at.
+// A check with a zero score is turned off.
+type Rules struct {
+	Duplicate  DuplicateRule `json:"duplicate"`
+	Links      LinkRule      `json:"links"`
+	Velocity   VelocityRule  `json:"velocity"`
+	Thresholds Thresholds    `json:"thresholds"`
+	// leading zero bits a verification challenge's solution must have
+	Difficulty int `json:"verification_difficulty"`
+}
+
+// DuplicateRule flags chirps whose simhash is within Distance bits of one of
+// the author's recent chirps. Each further copy adds half the score again,
+// up to twice the score.
+type DuplicateRule struct {
+	Score    float64 `json:"score"`
+	Distance int     `json:"distance"`
+	Lookback int     `json:"lookback"`
+}
+
+// LinkRule flags chirps with more than MaxLinks links, or where links make
+// up more than MaxRatio of the words.
+type LinkRule struct {
+	Score    float64 `json:"score"`
+	MaxLinks int     `json:"max_links"`
+	MaxRatio float64 `json:"max_ratio"`
+}
+
+// VelocityRule flags accounts younger than NewAccount that have posted
+// MaxChirps or more within Window.
+type VelocityRule struct {
+	Score      float64  `json:"score"`
+	NewAccount Duration `json:"new_account"`
+	Window     Duration `json:"window"`
+	MaxChirps  int      `json:"max_chirps"`
+}
+
+type Thresholds struct {
+	Verify float64 `json:"verify"`
+	Hold   float64 `json:"hold"`
+	Reject float64 `json:"reject"`
+}
+
+func DefaultRules() Rules {
+	return Rules{
+		Duplicate:  DuplicateRule{Score: 0.6, Distance: 8, Lookback: 20},
+		Links:      LinkRule{Score: 0.4, MaxLinks: 2, MaxRatio: 0.5},
+		Velocity:   VelocityRule{Score: 0.5, NewAccount: Duration{24 * time.Hour}, Window: Duration{time.Hour}, MaxChirps: 10},
+		Thresholds: Thresholds{Verify: 0.5, Hold: 0.9, Reject: 1.4},
+		Difficulty: 18,
+	}
+}
+
+// LoadRules reads rules as JSON. Anything left out keeps its default.
+func LoadRules(r io.Reader) (Rules, error) {
+	rules := DefaultRules()
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&rules)
+	if err != nil {
+		return rules, err
+	}
+	return rules, rules.validate()
+}
+
+func (rules Rules) validate() error {
+	t := rules.Thresholds
+	if t.Verify <= 0 || t.Verify > t.Hold || t.Hold > t.Reject {
+		return errors.New("thresholds must be positive and ordered verify <= hold <= reject")
+	}
+	if rules.Duplicate.Distance < 0 || rules.Duplicate.Distance > 64 || rules.Duplicate.Lookback < 0 {
+		return errors.New("duplicate distance must be 0-64 and lookback not negative")
+	}
+	if rules.Links.MaxLinks < 0 || rules.Links.MaxRatio <= 0 {
+		return errors.New("links max_links must not be negative and max_ratio must be positive")
+	}
+	if rules.Velocity.Window.Duration <= 0 || rules.Velocity.MaxChirps <= 0 {
+		return errors.New("velocity window and max_chirps must be positive")
+	}
+	if rules.Difficulty < 1 || rules.Difficulty > 32 {
+		return errors.New("verification_difficulty must be 1-32")
+	}
+	return nil
+}
+
+// Pipeline runs every check over a chirp and turns the total score into an
+// action.
+type Pipeline struct {
+	rules  Rules
+	checks []Check
+}
+
+// New builds a pipeline from the built in checks configured by rules,
+// followed by any extra ones.
+func New(rules Rules, extra ...Check) *Pipeline {
+	p := &Pipeline{rules: rules}
+	if rules.Duplicate.Score > 0 {
+		p.checks = append(p.checks, duplicateCheck(rules.Duplicate))
+	}
+	if rules.Links.Score > 0 {
+		p.checks = append(p.checks, linkCheck(rules.Links))
+	}
+	if rules.Velocity.Score > 0 {
+		p.checks = append(p.checks, velocityCheck(rules.Velocity))
+	}
+	p.checks = append(p.checks, extra...)
+	return p
+}
+
+func (p *Pipeline) Rules() Rules {
+	return p.rules
+}
+
+func (p *Pipeline) Evaluate(in Input) Verdict {
+	v := Verdict{Action: Allow}
+	for _, check := range p.checks {
+		signal := check(in)
+		if signal.Score <= 0 {
+			continue
+		}
+		v.Score += signal.Score
+		v.Signals = append(v.Signals, signal)
+	}
+	t := p.rules.Thresholds
+	switch {
+	case v.Score >= t.Reject:
+		v.Action = Reject
+	case v.Score >= t.Hold:
+		v.Action = Hold
+	case v.Score >= t.Verify:
+		v.Action = Verify
+	}
+	return v
+}
+
+func duplicateCheck(rule DuplicateRule) Check {
+	return func(in Input) Signal {
+		if strings.TrimSpace(in.Body) == "" {
+			return Signal{}
+		}
+		hash := Simhash(in.Body)
+		copies := 0
+		for _, body := range in.Recent {
+			if strings.TrimSpace(body) != "" && Distance(hash, Simhash(body)) <= rule.Distance {
+				copies++
+			}
+		}
+		if copies == 0 {
+			return Signal{}
+		}
+		score := min(rule.Score*float64(1+copies)/2, rule.Score*2)
+		return Signal{Check: "duplicate", Score: score, Reason: fmt.Sprintf("matches %d of the author's recent chirps", copies)}
+	}
+}
+
+func linkCheck(rule LinkRule) Check {
+	return func(in Input) Signal {
+		if in.Links == 0 {
+			return Signal{}
+		}
+		if in.Links > rule.MaxLinks {
+			return Signal{Check: "links", Score: rule.Score, Reason: fmt.Sprintf("has %d links", in.Links)}
+		}
+		words := len(strings.Fields(in.Body))
+		if ratio := float64(in.Links) / float64(max(words, 1)); ratio > rule.MaxRatio {
+			return Signal{Check: "links", Score: rule.Score, Reason: fmt.Sprintf("links make up %.0f%% of the words", ratio*100)}
+		}
+		return Signal{}
+	}
+}
+
+func velocityCheck(rule VelocityRule) Check {
+	return func(in Input) Signal {
+		if in.AccountAge >= rule.NewAccount.Duration || in.RecentCount < rule.MaxChirps {
+			return Signal{}
+		}
+		return Signal{Check: "velocity", Score: rule.Score, Reason: fmt.Sprintf("%d chirps within %s from an account %s old", in.RecentCount, rule.Window, in.AccountAge.Round(time.Minute))}
+	}
+}
@@ -0,0 +1,116 @@
+package spam
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSimhash(t *testing.T) {
+	base := "Get free followers now at our amazing site, limited time offer for everyone who signs up today"
+	near := []string{
+		"GET FREE FOLLOWERS now at our amazing site! Limited time offer for everyone who signs up today",
+		"Get free followers now at our amazing site, limited time offer for everyone who signs up today 4821",
+		"Get free followers now at our great site, limited time offer for everyone who signs up today",
+	}
+	for _, text := range near {
+		if d := Distance(Simhash(base), Simhash(text)); d > DefaultRules().Duplicate.Distance {
+			t.Errorf("Recieved distance %d for %q, expected a near duplicate", d, text)
+		}
+	}
+	far := "I had a lovely walk in the park this morning and saw some ducks"
+	if d := Distance(Simhash(base), Simhash(far)); d <= DefaultRules().Duplicate.Distance {
+		t.Errorf("Recieved distance %d for unrelated text, expected more than %d", d, DefaultRules().Duplicate.Distance)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	p := New(DefaultRules())
+	flood := "Win a free phone, just click the link in our bio and enter your details"
+	cases := []struct {
+		name   string
+		in     Input
+		action string
+	}{
+		{"clean", Input{Body: "Lovely weather for a walk today", AccountAge: time.Hour, Recent: []string{"Good morning everyone"}}, Allow},
+		{"one copy", Input{Body: flood, AccountAge: 30 * 24 * time.Hour, Recent: []string{flood}}, Verify},
+		{"several copies", Input{Body: flood, AccountAge: 30 * 24 * time.Hour, Recent: []string{flood, flood + "!", flood + " now"}}, Hold},
+		{"copies and links", Input{Body: flood, Links: 3, AccountAge: 30 * 24 * time.Hour, Recent: []string{flood, flood, flood}}, Reject},
+		{"new account flooding", Input{Body: flood, AccountAge: 10 * time.Minute, RecentCount: 40, Recent: []string{flood, flood, flood}}, Reject},
+		{"busy old account", Input{Body: "Live thoughts on the match", AccountAge: 365 * 24 * time.Hour, RecentCount: 40}, Allow},
+	}
+	for _, c := range cases {
+		v := p.Evaluate(c.in)
+		if v.Action != c.action {
+			t.Errorf("%s: Recieved %s with score %.2f (%v), expected %s", c.name, v.Action, v.Score, v.Signals, c.action)
+		}
+	}
+}
+
+func TestExtraCheck(t *testing.T) {
+	banned := func(in Input) Signal {
+		if strings.Contains(in.Body, "crypto giveaway") {
+			return Signal{Check: "phrase", Score: 2, Reason: "banned phrase"}
+		}
+		return Signal{}
+	}
+	p := New(DefaultRules(), banned)
+	v := p.Evaluate(Input{Body: "Huge crypto giveaway, reply to enter", AccountAge: time.Hour})
+	if v.Action != Reject || len(v.Signals) != 1 || v.Signals[0].Check != "phrase" {
+		t.Errorf("Recieved %+v, expected a rejection from the extra check", v)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	rules, err := LoadRules(strings.NewReader(`{"links": {"score": 0}, "velocity": {"window": "30m"}, "thresholds": {"verify": 0.4, "hold": 1, "reject": 2}}`))
+	if err != nil {
+		t.Fatalf("LoadRules failed: %v", err)
+	}
+	if rules.Links.Score != 0 || rules.Links.MaxLinks != DefaultRules().Links.MaxLinks {
+		t.Errorf("Recieved link rule %+v, expected the score off and other defaults kept", rules.Links)
+	}
+	if rules.Velocity.Window.Duration != 30*time.Minute {
+		t.Errorf("Recieved window %s, expected 30m", rules.Velocity.Window)
+	}
+	v := New(rules).Evaluate(Input{Body: "a http://a.example b http://b.example c http://c.example", Links: 3})
+	if len(v.Signals) != 0 {
+		t.Errorf("Recieved %v, expected the link check to be off", v.Signals)
+	}
+	for _, bad := range []string{`{"thresholds": {"verify": 2, "hold": 1, "reject": 3}}`, `{"velocity": {"window": "soon"}}`, `{"unknown": 1}`} {
+		_, err := LoadRules(strings.NewReader(bad))
+		if err == nil {
+			t.Errorf("LoadRules(%s) should fail", bad)
+		}
+	}
+}
+
+func TestChallenge(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Now()
+	challenge := NewChallenge(secret, "user:body", 8, now.Add(time.Minute))
+	solution, err := Solve(challenge)
+	if err != nil {
+		t.Fatalf("Solve failed: %v", err)
+	}
+	err = VerifyChallenge(secret, "user:body", challenge, solution, now)
+	if err != nil {
+		t.Errorf("VerifyChallenge failed for a valid solution: %v", err)
+	}
+	cases := []struct {
+		name                         string
+		secret                       []byte
+		subject, challenge, solution string
+		now                          time.Time
+	}{
+		{"other subject", secret, "user:other body", challenge, solution, now},
+		{"other secret", []byte("other"), "user:body", challenge, solution, now},
+		{"expired", secret, "user:body", challenge, solution, now.Add(2 * time.Minute)},
+		{"easier", secret, "user:body", strings.Replace(challenge, ".8.", ".0.", 1), "x", now},
+		{"malformed", secret, "user:body", "nonsense", solution, now},
+	}
+	for _, c := range cases {
+		if VerifyChallenge(c.secret, c.subject, c.challenge, c.solution, c.now) == nil {
+			t.Errorf("%s: VerifyChallenge should fail", c.name)
+		}
+	}
+}
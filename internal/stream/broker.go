@@ -0,0 +1,101 @@
+package stream
+
+import "sync"
+
+// Event is a message delivered to subscribers. IDs increase across the whole
+// deployment, so a client can resume after the last ID it saw.
+type Event struct {
+	ID     int64
+	Type   string
+	Data   []byte
+	Topics []string
+}
+
+// Broker fans events out to in-process subscribers by topic. Publishing never
+// blocks: a subscriber whose buffer is full is dropped and its channel closed,
+// leaving it to reconnect and catch up from its last event ID.
+type Broker struct {
+	mu     sync.Mutex
+	buffer int
+	topics map[string]map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	// Events is closed when the subscription ends, either by Close or
+	// because the subscriber fell too far behind.
+	Events <-chan Event
+
+	broker  *Broker
+	events  chan Event
+	topics  []string
+	closed  bool
+	dropped bool
+}
+
+func NewBroker(buffer int) *Broker {
+	return &Broker{buffer: buffer, topics: map[string]map[*Subscription]struct{}{}}
+}
+
+// Subscribe receives every event published to any of topics.
+func (b *Broker) Subscribe(topics []string) *Subscription {
+	events := make(chan Event, b.buffer)
+	sub := &Subscription{Events: events, broker: b, events: events, topics: topics}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		if b.topics[topic] == nil {
+			b.topics[topic] = map[*Subscription]struct{}{}
+		}
+		b.topics[topic][sub] = struct{}{}
+	}
+	return sub
+}
+
+// Publish delivers e at most once to each subscriber of any of its topics.
+func (b *Broker) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delivered := map[*Subscription]bool{}
+	for _, topic := range e.Topics {
+		for sub := range b.topics[topic] {
+			if delivered[sub] {
+				continue
+			}
+			delivered[sub] = true
+			select {
+			case sub.events <- e:
+			default:
+				sub.dropped = true
+				b.remove(sub)
+			}
+		}
+	}
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+// Dropped reports whether the subscription was ended for falling behind.
+func (s *Subscription) Dropped() bool {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	return s.dropped
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	for _, topic := range sub.topics {
+		delete(b.topics[topic], sub)
+		if len(b.topics[topic]) == 0 {
+			delete(b.topics, topic)
+		}
+	}
+	close(sub.events)
+}
@@ -0,0 +1,64 @@
+package stream
+
+import "testing"
+
+func TestPublishByTopic(t *testing.T) {
+	broker := NewBroker(4)
+	global := broker.Subscribe([]string{"global"})
+	defer global.Close()
+	both := broker.Subscribe([]string{"user:a", "hashtag:go"})
+	defer both.Close()
+	other := broker.Subscribe([]string{"user:b"})
+	defer other.Close()
+
+	broker.Publish(Event{ID: 1, Topics: []string{"global", "user:a", "hashtag:go"}})
+
+	if e := <-global.Events; e.ID != 1 {
+		t.Errorf("Recieved event %d, expected 1", e.ID)
+	}
+	if e := <-both.Events; e.ID != 1 {
+		t.Errorf("Recieved event %d, expected 1", e.ID)
+	}
+	if len(both.Events) != 0 {
+		t.Error("Subscriber matching several topics should receive an event once")
+	}
+	if len(other.Events) != 0 {
+		t.Error("Subscriber should not receive events for other topics")
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	broker := NewBroker(2)
+	slow := broker.Subscribe([]string{"global"})
+	for i := int64(1); i <= 3; i++ {
+		broker.Publish(Event{ID: i, Topics: []string{"global"}})
+	}
+	if !slow.Dropped() {
+		t.Fatal("Subscriber with a full buffer should be dropped")
+	}
+	count := 0
+	for range slow.Events {
+		count++
+	}
+	if count != 2 {
+		t.Errorf("Recieved %d buffered events, expected 2", count)
+	}
+	slow.Close()
+	broker.Publish(Event{ID: 4, Topics: []string{"global"}})
+}
+
+func TestClose(t *testing.T) {
+	broker := NewBroker(1)
+	sub := broker.Subscribe([]string{"global"})
+	sub.Close()
+	sub.Close()
+	if _, ok := <-sub.Events; ok {
+		t.Error("Events should be closed after Close")
+	}
+	if sub.Dropped() {
+		t.Error("Closed subscription should not report as dropped")
+	}
+	if len(broker.topics) != 0 {
+		t.Error("Broker should forget closed subscriptions")
+	}
+}
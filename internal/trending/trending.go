@@ -0,0 +1,53 @@
+package trending
+
+import (
+	"math"
+	"sort"
+)
+
+// Usage is how often a tag was used in the current window and in the window
+// of equal length just before it.
+type Usage struct {
+	Tag      string
+	Current  int
+	Previous int
+}
+
+type Scored struct {
+	Tag   string
+	Count int
+	Score float64
+}
+
+// Score favours tags whose usage is accelerating: the growth ratio against
+// the previous window, damped by log volume so a jump from 1 to 3 chirps
+// doesn't outrank a tag that went from 200 to 400.
+func Score(u Usage) float64 {
+	growth := float64(u.Current+1) / float64(u.Previous+1)
+	return math.Log1p(float64(u.Current)) * growth
+}
+
+// Rank scores usages and returns the top limit tags that were used at least
+// minCount times in the current window.
+func Rank(usages []Usage, minCount, limit int) []Scored {
+	ranked := []Scored{}
+	for _, u := range usages {
+		if u.Current < minCount || u.Current <= u.Previous/2 {
+			continue
+		}
+		ranked = append(ranked, Scored{Tag: u.Tag, Count: u.Current, Score: Score(u)})
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		if ranked[i].Count != ranked[j].Count {
+			return ranked[i].Count > ranked[j].Count
+		}
+		return ranked[i].Tag < ranked[j].Tag
+	})
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
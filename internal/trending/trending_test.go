@@ -0,0 +1,31 @@
+package trending
+
+import "testing"
+
+func TestRank(t *testing.T) {
+	usages := []Usage{
+		{Tag: "steady", Current: 100, Previous: 100},
+		{Tag: "rising", Current: 60, Previous: 10},
+		{Tag: "tiny", Current: 2, Previous: 0},
+		{Tag: "dying", Current: 5, Previous: 80},
+	}
+	ranked := Rank(usages, 3, 10)
+	if len(ranked) != 2 {
+		t.Fatalf("Recieved %d tags, expected 2: %v", len(ranked), ranked)
+	}
+	if ranked[0].Tag != "rising" || ranked[1].Tag != "steady" {
+		t.Errorf("Recieved order %v, expected rising before steady", ranked)
+	}
+	if len(Rank(usages, 3, 1)) != 1 {
+		t.Error("Rank should respect limit")
+	}
+}
+
+func TestScoreVelocity(t *testing.T) {
+	if Score(Usage{Current: 50, Previous: 5}) <= Score(Usage{Current: 50, Previous: 50}) {
+		t.Error("Accelerating tag should score higher than a flat one with the same volume")
+	}
+	if Score(Usage{Current: 400, Previous: 200}) <= Score(Usage{Current: 3, Previous: 1}) {
+		t.Error("Volume should dampen growth from tiny counts")
+	}
+}
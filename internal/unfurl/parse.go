@@ -0,0 +1,107 @@
+package unfurl
+
+import (
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	maxTitleLength       = 300
+	maxDescriptionLength = 1000
+)
+
+type Preview struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+// Parse reads OpenGraph and Twitter card meta tags from an html document,
+// falling back to <title> and the description meta tag. Relative image URLs
+// are resolved against base.
+func Parse(r io.Reader, base *url.URL) (Preview, error) {
+	meta := map[string]string{}
+	var title string
+	z := html.NewTokenizer(r)
+	inTitle := false
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return Preview{}, z.Err()
+			}
+			return buildPreview(meta, title, base), nil
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch atom.Lookup(name) {
+			case atom.Body:
+				// everything a preview needs lives in <head>
+				return buildPreview(meta, title, base), nil
+			case atom.Title:
+				inTitle = tt == html.StartTagToken
+			case atom.Meta:
+				var key, content string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(string(v))
+					case "content":
+						content = string(v)
+					}
+				}
+				if _, ok := meta[key]; key != "" && !ok {
+					meta[key] = strings.TrimSpace(content)
+				}
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+		case html.EndTagToken:
+			inTitle = false
+		}
+	}
+}
+
+func buildPreview(meta map[string]string, title string, base *url.URL) Preview {
+	first := func(keys ...string) string {
+		for _, key := range keys {
+			if v := meta[key]; v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	preview := Preview{
+		URL:         base.String(),
+		Title:       truncate(first("og:title", "twitter:title"), maxTitleLength),
+		Description: truncate(first("og:description", "twitter:description", "description"), maxDescriptionLength),
+		SiteName:    truncate(first("og:site_name"), maxTitleLength),
+	}
+	if preview.Title == "" {
+		preview.Title = truncate(title, maxTitleLength)
+	}
+	if image := first("og:image:secure_url", "og:image", "twitter:image", "twitter:image:src"); image != "" {
+		if u, err := base.Parse(image); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			preview.ImageURL = u.String()
+		}
+	}
+	return preview
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
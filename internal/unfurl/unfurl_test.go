@@ -0,0 +1,104 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPage = `<!doctype html><html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="Chirpy launches">
+<meta name="twitter:description" content="A tiny social network">
+<meta property="og:image" content="/static/card.png">
+<meta property="og:site_name" content="Chirpy Blog">
+</head><body><meta property="og:title" content="ignored"></body></html>`
+
+func TestParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/1")
+	preview, err := Parse(strings.NewReader(testPage), base)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	expected := Preview{URL: "https://example.com/posts/1", Title: "Chirpy launches", Description: "A tiny social network", ImageURL: "https://example.com/static/card.png", SiteName: "Chirpy Blog"}
+	if preview != expected {
+		t.Errorf("Recieved %+v, expected %+v", preview, expected)
+	}
+	preview, _ = Parse(strings.NewReader("<html><head><title> Just a title </title></head></html>"), base)
+	if preview.Title != "Just a title" {
+		t.Errorf("Recieved title %q, expected the <title> fallback", preview.Title)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	for _, addr := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "::1", "fd00::1", "::ffff:127.0.0.1", "100.64.0.1", "0.0.0.0"} {
+		if IsPublic(netip.MustParseAddr(addr)) {
+			t.Errorf("%s should not be public", addr)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:4700::1111"} {
+		if !IsPublic(netip.MustParseAddr(addr)) {
+			t.Errorf("%s should be public", addr)
+		}
+	}
+}
+
+func testFetcher() *Fetcher {
+	return NewFetcher(Options{Timeout: 2 * time.Second, MaxBytes: 1 << 16, MaxRedirects: 2, UserAgent: "test"})
+}
+
+func TestFetchBlocksLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Blocked address was fetched")
+	}))
+	defer server.Close()
+	_, err := testFetcher().Fetch(context.Background(), server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Expected ErrBlockedAddress, recieved %v", err)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/page", http.StatusFound)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("{}"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	fetcher := testFetcher()
+	fetcher.allowAddr = func(netip.Addr) bool { return true }
+
+	preview, err := fetcher.Fetch(context.Background(), server.URL+"/start")
+	if err != nil {
+		t.Fatalf("Failed to fetch: %v", err)
+	}
+	if preview.Title != "Chirpy launches" || preview.URL != server.URL+"/page" {
+		t.Errorf("Recieved %+v, expected the redirected page's preview", preview)
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/loop"); err == nil {
+		t.Error("Redirect loop should fail")
+	}
+	if _, err := fetcher.Fetch(context.Background(), server.URL+"/json"); !errors.Is(err, ErrNotHTML) {
+		t.Errorf("Expected ErrNotHTML, recieved %v", err)
+	}
+	if _, err := fetcher.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("Non http scheme should fail")
+	}
+}
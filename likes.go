@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+func (cfg *apiConfig) likeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setLike(w, r, true)
+}
+
+func (cfg *apiConfig) unlikeChirpHandler(w http.ResponseWriter, r *http.Request) {
+	cfg.setLike(w, r, false)
+}
+
+func (cfg *apiConfig) setLike(w http.ResponseWriter, r *http.Request, like bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	visible, err := canView(r.Context(), cfg.dbQueries, dbChirp, userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbChirp.DeletedAt.Valid || dbChirp.PublishAt.Valid || !visible {
+		w.WriteHeader(404)
+		return
+	}
+	// the count only moves when the like row was actually inserted or removed,
+	// so repeating a request is harmless
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		params := database.LikeChirpParams{UserID: userID, ChirpID: chirpID}
+		if like {
+			n, err := q.LikeChirp(r.Context(), params)
+			if err != nil || n == 0 {
+				return err
+			}
+			err = q.IncrementLikeCount(r.Context(), chirpID)
+			if err != nil {
+				return err
+			}
+			return cfg.notify(r.Context(), q, dbChirp.UserID, userID, notificationLike, chirpID)
+		}
+		n, err := q.UnlikeChirp(r.Context(), database.UnlikeChirpParams(params))
+		if err != nil || n == 0 {
+			return err
+		}
+		return q.DecrementLikeCount(r.Context(), chirpID)
+	})
+	if err != nil {
+		fmt.Printf("Error updating like:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) userLikesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	_, err = cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	viewerID := cfg.viewerID(r)
+	likes, err := cfg.dbQueries.GetUserLikes(r.Context(), database.GetUserLikesParams{UserID: userID, ViewerID: viewerID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+	if err != nil {
+		fmt.Printf("Error getting likes:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(likes))
+	for _, like := range likes {
+		ids = append(ids, like.ChirpID)
+	}
+	dbChirps, err := cfg.getChirpsInOrder(r.Context(), viewerID, ids)
+	if err != nil {
+		fmt.Printf("Error getting chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	chirps, err := cfg.renderChirps(r.Context(), viewerID, dbChirps)
+	if err != nil {
+		fmt.Printf("Error rendering chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	page := ChirpPage{Chirps: chirps}
+	if len(likes) > 0 {
+		last := likes[len(likes)-1]
+		page.NextCursor = nextCursor(len(likes), limit, pageCursor{Time: last.LikedAt, ID: last.ChirpID})
+	}
+	respondWithJSON(w, 200, page)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/ratelimit"
+)
+
+// What a policy's buckets are keyed on. Requests to user keyed routes without
+// a valid token fall back to the client's address.
+const (
+	limitByUser = "user"
+	limitByIP   = "ip"
+)
+
+// limitPolicy is a rate limit as written in the RATE_LIMITS file.
+type limitPolicy struct {
+	Limit  int    `json:"limit"`
+	Period string `json:"period"`
+	Key    string `json:"key"`
+}
+
+// Every route is limited by the policy named here, or by "read" or "write"
+// depending on its method. An empty name leaves a route unlimited.
+var defaultLimitPolicies = map[string]limitPolicy{
+	"read":    {Limit: 300, Period: "1m", Key: limitByUser},
+	"write":   {Limit: 60, Period: "1m", Key: limitByUser},
+	"signup":  {Limit: 5, Period: "1h", Key: limitByIP},
+	"login":   {Limit: 10, Period: "15m", Key: limitByIP},
+	"refresh": {Limit: 30, Period: "1h", Key: limitByIP},
+	"chirp":   {Limit: 30, Period: "10m", Key: limitByUser},
+	"media":   {Limit: 20, Period: "1h", Key: limitByUser},
+	"report":  {Limit: 20, Period: "1h", Key: limitByUser},
+	"message": {Limit: 60, Period: "1m", Key: limitByUser},
+}
+
+var defaultLimitRoutes = map[string]string{
+	"GET /api/healthz":                                  "",
+	"POST /api/users":                                   "signup",
+	"POST /api/login":                                   "login",
+	"POST /api/refresh":                                 "refresh",
+	"POST /api/chirps":                                  "chirp",
+	"POST /api/drafts/{draftID}/publish":                "chirp",
+	"POST /api/media":                                   "media",
+	"PUT /api/users/avatar":                             "media",
+	"POST /api/chirps/{chirpID}/reports":                "report",
+	"POST /api/users/{handle}/reports":                  "report",
+	"POST /api/conversations":                           "message",
+	"POST /api/conversations/{conversationID}/messages": "message",
+}
+
+type rateLimiter struct {
+	store    ratelimit.Store
+	policies map[string]ratelimit.Policy
+	keys     map[string]string
+	routes   map[string]string
+	proxies  ratelimit.Proxies
+}
+
+// newRateLimiter builds the limiter from the defaults above, overridden by
+// the JSON file at RATE_LIMITS, e.g.
+//
+//	{"policies": {"login": {"limit": 5, "period": "15m", "key": "ip"}},
+//	 "routes": {"GET /api/timeline": "timeline"}}
+func newRateLimiter(db *sql.DB, q *database.Queries) (*rateLimiter, error) {
+	type config struct {
+		Policies map[string]limitPolicy `json:"policies"`
+		Routes   map[string]string      `json:"routes"`
+	}
+	conf := config{Policies: map[string]limitPolicy{}, Routes: map[string]string{}}
+	for name, policy := range defaultLimitPolicies {
+		conf.Policies[name] = policy
+	}
+	for route, name := range defaultLimitRoutes {
+		conf.Routes[route] = name
+	}
+	if path := os.Getenv("RATE_LIMITS"); path != "" {
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(dat, &conf)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RATE_LIMITS %s: %w", path, err)
+		}
+	}
+	proxies, err := ratelimit.ParseProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return nil, err
+	}
+	l := &rateLimiter{policies: map[string]ratelimit.Policy{}, keys: map[string]string{}, routes: conf.Routes, proxies: proxies}
+	longest := time.Duration(0)
+	for name, policy := range conf.Policies {
+		period, err := time.ParseDuration(policy.Period)
+		if err != nil || period <= 0 || policy.Limit <= 0 {
+			return nil, fmt.Errorf("invalid rate limit %q, expected a positive limit and period", name)
+		}
+		if policy.Key != limitByUser && policy.Key != limitByIP {
+			return nil, fmt.Errorf("invalid rate limit %q, key must be user or ip", name)
+		}
+		l.policies[name] = ratelimit.Policy{Limit: policy.Limit, Period: period}
+		l.keys[name] = policy.Key
+		longest = max(longest, period)
+	}
+	for route, name := range conf.Routes {
+		if _, ok := l.policies[name]; name != "" && !ok {
+			return nil, fmt.Errorf("rate limit %q for %s is not defined", name, route)
+		}
+	}
+	for _, name := range []string{"read", "write"} {
+		if _, ok := l.policies[name]; !ok {
+			return nil, fmt.Errorf("rate limit %q must be defined", name)
+		}
+	}
+	switch store := os.Getenv("RATE_LIMIT_STORE"); store {
+	case "", "memory":
+		l.store = ratelimit.NewMemoryStore()
+	case "postgres":
+		l.store = &postgresLimitStore{db: db, q: q, idle: longest}
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q, expected memory or postgres", store)
+	}
+	return l, nil
+}
+
+// limitRequests applies the policy of the route each request is for before
+// handing it to mux.
+func (cfg *apiConfig) limitRequests(mux *http.ServeMux) http.Handler {
+	l := cfg.limiter
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, route := mux.Handler(r)
+		name, ok := l.routes[route]
+		if !ok {
+			name = "write"
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				name = "read"
+			}
+		}
+		if name == "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		key := name + ":ip:" + ratelimit.IPKey(l.proxies.ClientIP(r))
+		if l.keys[name] == limitByUser {
+			if userID := cfg.viewerID(r); userID != uuid.Nil {
+				key = name + ":user:" + userID.String()
+			}
+		}
+		policy := l.policies[name]
+		res, err := l.store.Take(r.Context(), key, policy)
+		if err != nil {
+			// an unavailable store shouldn't take the whole API down with it
+			fmt.Printf("Error checking rate limit:%v\n", err.Error())
+			mux.ServeHTTP(w, r)
+			return
+		}
+		res.SetHeaders(w.Header(), policy)
+		if !res.Allowed {
+			respondWithText(w, 429, "Too many requests, try again later")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// postgresLimitStore keeps buckets in the database so that every instance
+// shares them. Time is read from the database too, so instances' clocks
+// don't have to agree.
+type postgresLimitStore struct {
+	db   *sql.DB
+	q    *database.Queries
+	idle time.Duration
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func (s *postgresLimitStore) Take(ctx context.Context, key string, p ratelimit.Policy) (ratelimit.Result, error) {
+	s.sweep(ctx)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	defer tx.Rollback()
+	q := s.q.WithTx(tx)
+	row, err := q.LockRateLimit(ctx, database.LockRateLimitParams{Key: key, Tokens: float64(p.Limit)})
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	bucket, res := p.Take(ratelimit.Bucket{Tokens: row.Tokens, Updated: row.UpdatedAt}, row.Now)
+	err = q.SaveRateLimit(ctx, database.SaveRateLimitParams{Key: key, Tokens: bucket.Tokens, UpdatedAt: bucket.Updated})
+	if err != nil {
+		return ratelimit.Result{}, err
+	}
+	return res, tx.Commit()
+}
+
+// sweep deletes idle buckets, at most once a minute per instance.
+func (s *postgresLimitStore) sweep(ctx context.Context) {
+	s.mu.Lock()
+	if time.Since(s.lastSweep) < time.Minute {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = time.Now()
+	s.mu.Unlock()
+	err := s.q.DeleteIdleRateLimits(ctx, s.idle.Seconds())
+	if err != nil {
+		fmt.Printf("Error sweeping rate limits:%v\n", err.Error())
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/entities"
+	"github.com/leiper-mike/chirpy/internal/unfurl"
+)
+
+const (
+	maxLinksPerChirp   = 4
+	maxLinkLength      = 2048
+	linkPreviewMaxAge  = 7 * 24 * time.Hour
+	unfurlPollInterval = 5 * time.Second
+	unfurlBatchSize    = 5
+	unfurlLease        = time.Minute
+	unfurlRetryDelay   = 10 * time.Minute
+	unfurlMaxAttempts  = 3
+)
+
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	ImageURL    string `json:"image_url,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+func newUnfurler() *unfurl.Fetcher {
+	return unfurl.NewFetcher(unfurl.Options{Timeout: 5 * time.Second, MaxBytes: 512 << 10, MaxRedirects: 3, UserAgent: "Chirpy-LinkPreview/1.0"})
+}
+
+// normalizeLink canonicalizes a link so the same page shares one cached
+// preview, returning "" for anything that can't be fetched.
+func normalizeLink(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return ""
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.RawFragment = ""
+	s := u.String()
+	if len(s) > maxLinkLength {
+		return ""
+	}
+	return s
+}
+
+func chirpLinks(body string) []string {
+	links := []string{}
+	seen := map[string]bool{}
+	for _, found := range entities.URLs(body) {
+		link := normalizeLink(found.Text)
+		if link != "" && !seen[link] && len(links) < maxLinksPerChirp {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// linkChirp records the links in a new chirp and queues any without a fresh
+// cached preview for the unfurl worker. It reports whether there were any.
+func linkChirp(ctx context.Context, q *database.Queries, dbChirp database.Chirp) (bool, error) {
+	links := chirpLinks(dbChirp.Body)
+	if len(links) == 0 {
+		return false, nil
+	}
+	err := q.RequestLinkPreviews(ctx, database.RequestLinkPreviewsParams{Urls: links, MaxAgeSeconds: int32(linkPreviewMaxAge.Seconds())})
+	if err != nil {
+		return false, err
+	}
+	return true, q.AddChirpLinks(ctx, database.AddChirpLinksParams{ChirpID: dbChirp.ID, Urls: links})
+}
+
+// getLinkPreviews returns the card for the first link in each chirp that has
+// been unfurled successfully.
+func (cfg *apiConfig) getLinkPreviews(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID]*LinkPreview, error) {
+	rows, err := cfg.dbQueries.GetChirpLinkPreviews(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	previews := map[uuid.UUID]*LinkPreview{}
+	for _, row := range rows {
+		if _, ok := previews[row.ChirpID]; !ok {
+			previews[row.ChirpID] = &LinkPreview{URL: row.Url, Title: row.Title, Description: row.Description, ImageURL: row.ImageUrl, SiteName: row.SiteName}
+		}
+	}
+	return previews, nil
+}
+
+// wakeUnfurler tells the worker there is new work without waiting for its next poll.
+func (cfg *apiConfig) wakeUnfurler() {
+	select {
+	case cfg.unfurlWake <- struct{}{}:
+	default:
+	}
+}
+
+// runUnfurlWorker fetches queued link previews. Rows are claimed with SKIP
+// LOCKED and a lease, so several instances can run it and a crashed worker's
+// claims are retried once the lease runs out.
+func (cfg *apiConfig) runUnfurlWorker(ctx context.Context) {
+	ticker := time.NewTicker(unfurlPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-cfg.unfurlWake:
+		}
+		for cfg.unfurlBatch(ctx) {
+		}
+	}
+}
+
+// unfurlBatch processes one batch, reporting whether there may be more.
+func (cfg *apiConfig) unfurlBatch(ctx context.Context) bool {
+	claimed, err := cfg.dbQueries.ClaimLinkPreviews(ctx, database.ClaimLinkPreviewsParams{LeaseSeconds: int32(unfurlLease.Seconds()), RowLimit: unfurlBatchSize})
+	if err != nil {
+		fmt.Printf("Error claiming link previews: %v\n", err)
+		return false
+	}
+	var wg sync.WaitGroup
+	for _, link := range claimed {
+		wg.Add(1)
+		go func(link database.LinkPreview) {
+			defer wg.Done()
+			cfg.unfurlLink(ctx, link)
+		}(link)
+	}
+	wg.Wait()
+	return len(claimed) == unfurlBatchSize
+}
+
+func (cfg *apiConfig) unfurlLink(ctx context.Context, link database.LinkPreview) {
+	preview, err := cfg.unfurler.Fetch(ctx, link.Url)
+	if err != nil {
+		fmt.Printf("Error unfurling %s: %v\n", link.Url, err)
+		err = cfg.dbQueries.FailLinkPreview(ctx, database.FailLinkPreviewParams{MaxAttempts: unfurlMaxAttempts, RetrySeconds: int32(unfurlRetryDelay.Seconds()), Url: link.Url})
+		if err != nil {
+			fmt.Printf("Error recording unfurl failure: %v\n", err)
+		}
+		return
+	}
+	// a page without a title makes an empty card, so it counts as a failure
+	if preview.Title == "" {
+		err = cfg.dbQueries.FailLinkPreview(ctx, database.FailLinkPreviewParams{MaxAttempts: 0, RetrySeconds: 0, Url: link.Url})
+	} else {
+		err = cfg.dbQueries.CompleteLinkPreview(ctx, database.CompleteLinkPreviewParams{Url: link.Url, Title: preview.Title, Description: preview.Description, ImageUrl: preview.ImageURL, SiteName: preview.SiteName})
+	}
+	if err != nil {
+		fmt.Printf("Error saving link preview: %v\n", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -15,7 +16,12 @@ import (
 	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/leiper-mike/chirpy/internal/auth"
+	"github.com/leiper-mike/chirpy/internal/blob"
 	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/search"
+	"github.com/leiper-mike/chirpy/internal/spam"
+	"github.com/leiper-mike/chirpy/internal/stream"
+	"github.com/leiper-mike/chirpy/internal/unfurl"
 	_ "github.com/lib/pq"
 )
 
@@ -24,27 +30,57 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Email     string    `json:"email"`
+	Handle    string    `json:"handle,omitempty"`
 }
 type LoggedInUser struct {
 	ID           uuid.UUID `json:"id"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
 	Email        string    `json:"email"`
+	Handle       string    `json:"handle,omitempty"`
 	Token        string    `json:"token"`
 	RefreshToken string    `json:"refresh_token"`
 }
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID             uuid.UUID         `json:"id"`
+	CreatedAt      time.Time         `json:"created_at"`
+	UpdatedAt      time.Time         `json:"updated_at"`
+	Body           string            `json:"body"`
+	UserID         uuid.UUID         `json:"user_id"`
+	Author         *UserSummary      `json:"author,omitempty"`
+	InReplyToID    *uuid.UUID        `json:"in_reply_to_id,omitempty"`
+	PublishAt      *time.Time        `json:"publish_at,omitempty"`
+	ConversationID uuid.UUID         `json:"conversation_id"`
+	ReplyCount     int32             `json:"reply_count"`
+	LikeCount      int32             `json:"like_count"`
+	LikedByMe      *bool             `json:"liked_by_me,omitempty"`
+	RechirpCount   int32             `json:"rechirp_count"`
+	RechirpOf      *Chirp            `json:"rechirp_of,omitempty"`
+	QuoteOf        *Chirp            `json:"quoted_chirp,omitempty"`
+	Entities       *ChirpEntities    `json:"entities,omitempty"`
+	Media          []MediaAttachment `json:"media,omitempty"`
+	LinkPreview    *LinkPreview      `json:"link_preview,omitempty"`
+	Poll           *Poll             `json:"poll,omitempty"`
+	Edited         bool              `json:"edited"`
+	Visibility     string            `json:"visibility"`
+	Deleted        bool              `json:"deleted,omitempty"`
+	Filtered       *FilterMatch      `json:"filtered,omitempty"`
+	Highlights     []search.Range    `json:"highlights,omitempty"`
 }
 type apiConfig struct {
 	fileserverHits atomic.Int32
+	db             *sql.DB
 	dbQueries      *database.Queries
 	platform       string
 	secret         string
+	blobStore      blob.Store
+	timeline       timelineStrategy
+	broker         *stream.Broker
+	unfurler       *unfurl.Fetcher
+	unfurlWake     chan struct{}
+	editWindow     time.Duration
+	spam           *spam.Pipeline
+	limiter        *rateLimiter
 }
 
 func (cfg *apiConfig) middlewareMetricsInc(next http.Handler) http.Handler {
@@ -62,7 +98,32 @@ func main() {
 		os.Exit(1)
 	}
 	dbQueries := database.New(db)
-	apiCfg := apiConfig{fileserverHits: atomic.Int32{}, dbQueries: dbQueries, platform: os.Getenv("PLATFORM"), secret: os.Getenv("TOKEN_SECRET")}
+	blobStore, err := newBlobStore()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	timeline, err := newTimelineStrategy(os.Getenv("TIMELINE_STRATEGY"))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	editWindow, err := parseEditWindow(os.Getenv("CHIRP_EDIT_WINDOW"))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	spamRules, err := loadSpamRules(os.Getenv("SPAM_RULES"))
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	limiter, err := newRateLimiter(db, dbQueries)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	apiCfg := apiConfig{fileserverHits: atomic.Int32{}, db: db, dbQueries: dbQueries, platform: os.Getenv("PLATFORM"), secret: os.Getenv("TOKEN_SECRET"), blobStore: blobStore, timeline: timeline, broker: stream.NewBroker(streamBufferSize), unfurler: newUnfurler(), unfurlWake: make(chan struct{}, 1), editWindow: editWindow, spam: spam.New(spamRules), limiter: limiter}
 	serveMux := http.NewServeMux()
 	fileHandler := http.FileServer(http.Dir("./app"))
 	serveMux.Handle("/app/", http.StripPrefix("/app", apiCfg.middlewareMetricsInc(fileHandler)))
@@ -76,7 +137,76 @@ func main() {
 	serveMux.HandleFunc("POST /api/revoke", apiCfg.revokeHandler)
 	serveMux.HandleFunc("GET /api/chirps", apiCfg.getAllChirpsHandler)
 	serveMux.HandleFunc("GET /api/chirps/{chirpID}", apiCfg.getChirpHandler)
-	server := http.Server{Addr: ":8080", Handler: serveMux}
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirpHandler)
+	serveMux.HandleFunc("PATCH /api/chirps/{chirpID}", apiCfg.editChirpHandler)
+	serveMux.HandleFunc("GET /api/chirps/{chirpID}/history", apiCfg.chirpHistoryHandler)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/poll/votes", apiCfg.votePollHandler)
+	serveMux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiCfg.threadHandler)
+	serveMux.HandleFunc("GET /api/scheduled_chirps", apiCfg.scheduledChirpsHandler)
+	serveMux.HandleFunc("PUT /api/scheduled_chirps/{chirpID}", apiCfg.updateScheduledChirpHandler)
+	serveMux.HandleFunc("DELETE /api/scheduled_chirps/{chirpID}", apiCfg.cancelScheduledChirpHandler)
+	serveMux.HandleFunc("POST /api/drafts", apiCfg.createDraftHandler)
+	serveMux.HandleFunc("GET /api/drafts", apiCfg.draftsHandler)
+	serveMux.HandleFunc("GET /api/drafts/{draftID}", apiCfg.getDraftHandler)
+	serveMux.HandleFunc("PUT /api/drafts/{draftID}", apiCfg.updateDraftHandler)
+	serveMux.HandleFunc("DELETE /api/drafts/{draftID}", apiCfg.deleteDraftHandler)
+	serveMux.HandleFunc("POST /api/drafts/{draftID}/publish", apiCfg.publishDraftHandler)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/likes", apiCfg.likeChirpHandler)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/likes", apiCfg.unlikeChirpHandler)
+	serveMux.HandleFunc("GET /api/users/{id}/likes", apiCfg.userLikesHandler)
+	serveMux.HandleFunc("GET /api/users/{handle}", apiCfg.getProfileHandler)
+	serveMux.HandleFunc("PUT /api/users/profile", apiCfg.updateProfileHandler)
+	serveMux.HandleFunc("PUT /api/users/avatar", apiCfg.uploadAvatarHandler)
+	serveMux.HandleFunc("GET /media/{key...}", apiCfg.mediaHandler)
+	serveMux.HandleFunc("POST /api/media", apiCfg.uploadMediaHandler)
+	serveMux.HandleFunc("PUT /api/media/{mediaID}", apiCfg.updateMediaHandler)
+	serveMux.HandleFunc("POST /api/users/{handle}/follow", apiCfg.followHandler)
+	serveMux.HandleFunc("DELETE /api/users/{handle}/follow", apiCfg.unfollowHandler)
+	serveMux.HandleFunc("GET /api/users/{handle}/followers", apiCfg.followersHandler)
+	serveMux.HandleFunc("GET /api/users/{handle}/following", apiCfg.followingHandler)
+	serveMux.HandleFunc("POST /api/users/{handle}/block", apiCfg.blockHandler)
+	serveMux.HandleFunc("DELETE /api/users/{handle}/block", apiCfg.unblockHandler)
+	serveMux.HandleFunc("POST /api/users/{handle}/mute", apiCfg.muteHandler)
+	serveMux.HandleFunc("DELETE /api/users/{handle}/mute", apiCfg.unmuteHandler)
+	serveMux.HandleFunc("GET /api/blocks", apiCfg.blocksHandler)
+	serveMux.HandleFunc("GET /api/muted_words", apiCfg.mutedWordsHandler)
+	serveMux.HandleFunc("POST /api/muted_words", apiCfg.createMutedWordHandler)
+	serveMux.HandleFunc("DELETE /api/muted_words/{wordID}", apiCfg.deleteMutedWordHandler)
+	serveMux.HandleFunc("GET /api/mutes", apiCfg.mutesHandler)
+	serveMux.HandleFunc("GET /api/timeline", apiCfg.timelineHandler)
+	serveMux.HandleFunc("GET /api/search", apiCfg.searchHandler)
+	serveMux.HandleFunc("GET /api/hashtags/trending", apiCfg.trendingHashtagsHandler)
+	serveMux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiCfg.hashtagChirpsHandler)
+	serveMux.HandleFunc("GET /api/notifications", apiCfg.notificationsHandler)
+	serveMux.HandleFunc("GET /api/notifications/unread_count", apiCfg.unreadCountHandler)
+	serveMux.HandleFunc("POST /api/notifications/read", apiCfg.markReadHandler)
+	serveMux.HandleFunc("GET /api/notifications/preferences", apiCfg.getNotificationPreferencesHandler)
+	serveMux.HandleFunc("PUT /api/notifications/preferences", apiCfg.updateNotificationPreferencesHandler)
+	serveMux.HandleFunc("POST /api/conversations", apiCfg.createConversationHandler)
+	serveMux.HandleFunc("GET /api/conversations", apiCfg.conversationsHandler)
+	serveMux.HandleFunc("GET /api/conversations/{conversationID}/messages", apiCfg.messagesHandler)
+	serveMux.HandleFunc("POST /api/conversations/{conversationID}/messages", apiCfg.sendMessageHandler)
+	serveMux.HandleFunc("POST /api/conversations/{conversationID}/read", apiCfg.markConversationReadHandler)
+	serveMux.HandleFunc("POST /api/conversations/{conversationID}/leave", apiCfg.leaveConversationHandler)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/reports", apiCfg.reportChirpHandler)
+	serveMux.HandleFunc("POST /api/users/{handle}/reports", apiCfg.reportUserHandler)
+	serveMux.HandleFunc("GET /admin/reports", apiCfg.reportsHandler)
+	serveMux.HandleFunc("POST /admin/reports/{reportID}/assign", apiCfg.assignReportHandler)
+	serveMux.HandleFunc("POST /admin/reports/{reportID}/resolve", apiCfg.resolveReportHandler)
+	serveMux.HandleFunc("GET /admin/moderation_log", apiCfg.moderationLogHandler)
+	serveMux.HandleFunc("GET /admin/users/{handle}/account", apiCfg.getAccountHandler)
+	serveMux.HandleFunc("PUT /admin/users/{handle}/account", apiCfg.updateAccountHandler)
+	serveMux.HandleFunc("GET /admin/spam", apiCfg.spamChecksHandler)
+	serveMux.HandleFunc("POST /admin/spam/{checkID}/review", apiCfg.reviewSpamHandler)
+	serveMux.HandleFunc("GET /api/stream", apiCfg.streamHandler)
+	serveMux.HandleFunc("GET /api/ws", apiCfg.websocketHandler)
+	go apiCfg.runTrendingWorker(context.Background())
+	go apiCfg.runStreamRelay(context.Background(), dbURL)
+	go apiCfg.runMediaCleanup(context.Background())
+	go apiCfg.runUnfurlWorker(context.Background())
+	go apiCfg.runScheduler(context.Background())
+	go apiCfg.runPollCloser(context.Background())
+	server := http.Server{Addr: ":8080", Handler: apiCfg.limitRequests(serveMux)}
 	server.ListenAndServe()
 }
 
@@ -136,8 +266,10 @@ func cleanBody(str string) string {
 }
 func (cfg *apiConfig) addUserHandler(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
+		Email       string `json:"email"`
+		Password    string `json:"password"`
+		Handle      string `json:"handle"`
+		DisplayName string `json:"display_name"`
 	}
 	decoder := json.NewDecoder(r.Body)
 	params := parameters{}
@@ -147,19 +279,29 @@ func (cfg *apiConfig) addUserHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		return
 	}
+	handle := normalizeHandle(params.Handle)
+	err = validateProfileFields(handle, params.DisplayName, "")
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
 	hash, err := auth.HashPassword(params.Password)
 	if err != nil {
 		fmt.Print(err.Error())
 		w.WriteHeader(500)
 		return
 	}
-	dbUser, err := cfg.dbQueries.CreateUser(r.Context(), database.CreateUserParams{Email: params.Email, HashedPassword: hash})
+	dbUser, err := cfg.dbQueries.CreateUser(r.Context(), database.CreateUserParams{Email: params.Email, HashedPassword: hash, Handle: sql.NullString{String: handle, Valid: handle != ""}, DisplayName: params.DisplayName})
 	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			respondWithText(w, 409, "Email or handle is already taken")
+			return
+		}
 		fmt.Print(err.Error())
 		w.WriteHeader(500)
 		return
 	}
-	user := User{ID: dbUser.ID, CreatedAt: dbUser.CreatedAt.Time, UpdatedAt: dbUser.UpdatedAt.Time, Email: dbUser.Email}
+	user := User{ID: dbUser.ID, CreatedAt: dbUser.CreatedAt.Time, UpdatedAt: dbUser.UpdatedAt.Time, Email: dbUser.Email, Handle: dbUser.Handle.String}
 	dat, err := json.Marshal(user)
 	if err != nil {
 		fmt.Printf("Error marshalling JSON: %s", err)
@@ -174,7 +316,16 @@ func (cfg *apiConfig) addUserHandler(w http.ResponseWriter, r *http.Request) {
 
 func (cfg *apiConfig) postChirpHandler(w http.ResponseWriter, r *http.Request) {
 	type parameters struct {
-		Body string `json:"body"`
+		Body      string        `json:"body"`
+		InReplyTo uuid.NullUUID `json:"in_reply_to_id"`
+		RechirpOf uuid.NullUUID `json:"rechirp_of_id"`
+		QuoteOf   uuid.NullUUID `json:"quote_of_id"`
+		MediaIDs  []uuid.UUID   `json:"media_ids"`
+		Poll       *pollInput    `json:"poll"`
+		PublishAt  time.Time     `json:"publish_at"`
+		Visibility string        `json:"visibility"`
+		// the solved challenge, when a previous attempt asked for one
+		Verification *verificationInput `json:"verification"`
 	}
 	type errVals struct {
 		Error string `json:"error"`
@@ -208,42 +359,46 @@ func (cfg *apiConfig) postChirpHandler(w http.ResponseWriter, r *http.Request) {
 		w.Write(dat)
 		return
 	}
-	if len(params.Body) <= 140 {
-		cleanedBody := cleanBody(params.Body)
-		dbChirp, err := cfg.dbQueries.CreateChirp(context.Background(), database.CreateChirpParams{Body: params.Body, UserID: userId})
-		if err != nil {
-			fmt.Printf("Error creating chirp:%v\n", err.Error())
-			w.WriteHeader(500)
+	input := chirpInput{Body: params.Body, InReplyTo: params.InReplyTo, RechirpOf: params.RechirpOf, QuoteOf: params.QuoteOf, MediaIDs: params.MediaIDs, Poll: params.Poll, PublishAt: params.PublishAt, Visibility: params.Visibility, Verification: params.Verification}
+	dbChirp, err := cfg.createChirp(r.Context(), userId, input)
+	if err != nil {
+		var verifyErr *verificationError
+		if errors.As(err, &verifyErr) {
+			respondWithChallenge(w, verifyErr)
 			return
 		}
-		chirp := Chirp{ID: dbChirp.ID, CreatedAt: dbChirp.CreatedAt.Time, UpdatedAt: dbChirp.UpdatedAt.Time, Body: cleanedBody, UserID: dbChirp.UserID}
-		dat, err := json.Marshal(chirp)
-		if err != nil {
-			fmt.Printf("Error marshalling JSON: %s", err)
-			w.WriteHeader(500)
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(201)
-		w.Write(dat)
+		fmt.Printf("Error creating chirp:%v\n", err.Error())
+		w.WriteHeader(500)
 		return
-	} else {
-		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
-		w.WriteHeader(400)
-		w.Write([]byte("Chirp length must be no greater than 140 characters"))
 	}
+	cfg.respondWithOwnChirp(w, r, 201, userId, dbChirp)
 }
 func (cfg *apiConfig) getAllChirpsHandler(w http.ResponseWriter, r *http.Request) {
-	dbChirps, err := cfg.dbQueries.GetAllChirps(context.Background())
+	viewerID := cfg.viewerID(r)
+	dbChirps, err := cfg.dbQueries.GetAllChirps(context.Background(), viewerID)
 	if err != nil {
 		fmt.Printf("Error getting chirps:%v\n", err.Error())
 		w.WriteHeader(500)
 		return
 	}
-	chirps := make([]Chirp, 10)
-	for _, dbChirp := range dbChirps {
-		chirps = append(chirps, Chirp{ID: dbChirp.ID, CreatedAt: dbChirp.CreatedAt.Time, UpdatedAt: dbChirp.UpdatedAt.Time, Body: dbChirp.Body, UserID: dbChirp.UserID})
+	chirps, err := cfg.renderChirps(r.Context(), viewerID, dbChirps)
+	if err != nil {
+		fmt.Printf("Error rendering chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
 	}
+	mutedWords, err := cfg.mutedWordFilter(r.Context(), viewerID)
+	if err != nil {
+		fmt.Printf("Error loading muted words:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	chirps = filterChirps(mutedWords, viewerID, chirps)
 	sort.Slice(chirps, func(i, j int) bool {
 		return chirps[i].CreatedAt.Before(chirps[j].CreatedAt)
 	})
@@ -275,7 +430,24 @@ func (cfg *apiConfig) getChirpHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(500)
 		return
 	}
-	dat, err := json.Marshal(Chirp{ID: dbChirp.ID, CreatedAt: dbChirp.CreatedAt.Time, UpdatedAt: dbChirp.UpdatedAt.Time, Body: dbChirp.Body, UserID: dbChirp.UserID})
+	viewerID := cfg.viewerID(r)
+	visible, err := canView(r.Context(), cfg.dbQueries, dbChirp, viewerID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbChirp.DeletedAt.Valid || !visible {
+		w.WriteHeader(404)
+		return
+	}
+	chirp, err := cfg.renderChirp(r.Context(), viewerID, dbChirp)
+	if err != nil {
+		fmt.Printf("Error rendering chirp:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	dat, err := json.Marshal(chirp)
 	if err != nil {
 
 		fmt.Print(err.Error())
@@ -314,10 +486,8 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(401)
 		return
 	}
-	token, err := auth.MakeJWT(dbUser.ID, cfg.secret)
-	if err != nil {
-		fmt.Printf("Error creating token: %v", err)
-		w.WriteHeader(500)
+	if msg := lockedOut(dbUser); msg != "" {
+		respondWithText(w, 403, msg)
 		return
 	}
 	refreshToken, err := auth.MakeRefreshToken()
@@ -328,13 +498,19 @@ func (cfg *apiConfig) loginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	dur, _ := time.ParseDuration("1440h")
 	exp := time.Now().UTC().Add(dur)
-	_, err = cfg.dbQueries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{Token: refreshToken, UserID: dbUser.ID, ExpiresAt: exp})
+	rToken, err := cfg.dbQueries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{Token: refreshToken, UserID: dbUser.ID, ExpiresAt: exp})
 	if err != nil {
 		fmt.Printf("Error inserting refresh token: %v", err)
 		w.WriteHeader(500)
 		return
 	}
-	user := LoggedInUser{ID: dbUser.ID, CreatedAt: dbUser.CreatedAt.Time, UpdatedAt: dbUser.UpdatedAt.Time, Email: dbUser.Email, Token: token, RefreshToken: refreshToken}
+	token, err := auth.MakeSessionJWT(dbUser.ID, rToken.SessionID, cfg.secret)
+	if err != nil {
+		fmt.Printf("Error creating token: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	user := LoggedInUser{ID: dbUser.ID, CreatedAt: dbUser.CreatedAt.Time, UpdatedAt: dbUser.UpdatedAt.Time, Email: dbUser.Email, Handle: dbUser.Handle.String, Token: token, RefreshToken: refreshToken}
 	dat, err := json.Marshal(user)
 	if err != nil {
 		fmt.Printf("Error marshalling JSON: %s", err)
@@ -367,7 +543,17 @@ func (cfg *apiConfig) refreshHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(401)
 		return
 	}
-	newToken, err := auth.MakeJWT(rToken.UserID, cfg.secret)
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), rToken.UserID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if msg := lockedOut(dbUser); msg != "" {
+		respondWithText(w, 403, msg)
+		return
+	}
+	newToken, err := auth.MakeSessionJWT(rToken.UserID, rToken.SessionID, cfg.secret)
 	if err != nil {
 		fmt.Printf("Error creating token: %v", err)
 		w.WriteHeader(500)
@@ -392,10 +578,23 @@ func (cfg *apiConfig) revokeHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(400)
 		return
 	}
-	err = cfg.dbQueries.RevokeToken(context.Background(), token)
+	rToken, err := cfg.dbQueries.GetRefreshTokenByID(r.Context(), token)
 	if err != nil {
 		w.WriteHeader(401)
 		return
 	}
+	// live connections of the token's login are closed once the revocation commits
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.RevokeToken(r.Context(), token)
+		if err != nil {
+			return err
+		}
+		return publishEvent(r.Context(), q, database.CreateStreamEventParams{Type: streamEventSessionRevoked, Topics: []string{sessionTopic(rToken.SessionID)}})
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
 	w.WriteHeader(204)
 }
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/blob"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/imaging"
+)
+
+const (
+	maxMediaBytes     = 10 << 20
+	maxMediaPixels    = 4096 * 4096
+	maxGIFPixels      = 40 * 1000 * 1000
+	maxMediaPerChirp  = 4
+	maxAltTextLength  = 1000
+	mediaDisplaySize  = 2048
+	mediaThumbSize    = 400
+	mediaCleanupAge   = 24 * time.Hour
+	mediaCleanupBatch = 100
+)
+
+type MediaAttachment struct {
+	ID           uuid.UUID `json:"id"`
+	Type         string    `json:"type"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	Width        int32     `json:"width"`
+	Height       int32     `json:"height"`
+	AltText      string    `json:"alt_text"`
+}
+
+// newBlobStore picks where uploads are kept from BLOB_STORE: "local" (the
+// default) writes under MEDIA_ROOT, "s3" uses an S3 compatible bucket.
+func newBlobStore() (blob.Store, error) {
+	switch os.Getenv("BLOB_STORE") {
+	case "", "local":
+		mediaRoot := os.Getenv("MEDIA_ROOT")
+		if mediaRoot == "" {
+			mediaRoot = "./uploads"
+		}
+		return blob.NewLocalStore(mediaRoot)
+	case "s3":
+		return blob.NewS3Store(blob.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+		})
+	}
+	return nil, fmt.Errorf("unknown BLOB_STORE %q, expected local or s3", os.Getenv("BLOB_STORE"))
+}
+
+func mediaURL(key string) string {
+	return "/media/" + key
+}
+
+func mediaAttachment(dbMedia database.ChirpMedium) MediaAttachment {
+	return MediaAttachment{ID: dbMedia.ID, Type: dbMedia.Kind, URL: mediaURL(dbMedia.OriginalKey), ThumbnailURL: mediaURL(dbMedia.ThumbnailKey), Width: dbMedia.Width, Height: dbMedia.Height, AltText: dbMedia.AltText}
+}
+
+func (cfg *apiConfig) getMediaAttachments(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID][]MediaAttachment, error) {
+	rows, err := cfg.dbQueries.GetChirpMedia(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	attachments := map[uuid.UUID][]MediaAttachment{}
+	for _, row := range rows {
+		attachments[row.ChirpID.UUID] = append(attachments[row.ChirpID.UUID], mediaAttachment(row))
+	}
+	return attachments, nil
+}
+
+func (cfg *apiConfig) mediaHandler(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	visible, public, err := cfg.mediaAccess(r, key)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if !visible {
+		w.WriteHeader(404)
+		return
+	}
+	obj, err := cfg.blobStore.Get(r.Context(), key)
+	if err != nil {
+		if err == blob.ErrNotFound || err == blob.ErrInvalidKey {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	defer obj.Body.Close()
+	if public {
+		// keys are never reused for different content, so clients can cache forever
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		// who may see it can change, so it is checked again each time
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
+	w.Header().Set("Content-Type", obj.ContentType)
+	if obj.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(200)
+	io.Copy(w, obj.Body)
+}
+
+// mediaAccess reports whether the caller may load key, and whether anyone
+// may. Uploads (media/<user>/<media id>/...) are only visible where their
+// chirp is, and to their uploader until they are attached; other keys, such
+// as avatars, are public.
+func (cfg *apiConfig) mediaAccess(r *http.Request, key string) (visible, public bool, err error) {
+	parts := strings.Split(key, "/")
+	if parts[0] != "media" {
+		return true, true, nil
+	}
+	if len(parts) != 4 {
+		return false, false, nil
+	}
+	mediaID, err := uuid.Parse(parts[2])
+	if err != nil {
+		return false, false, nil
+	}
+	dbMedia, err := cfg.dbQueries.GetMedia(r.Context(), mediaID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return false, false, nil
+		}
+		return false, false, err
+	}
+	if dbMedia.OriginalKey != key && dbMedia.ThumbnailKey != key {
+		return false, false, nil
+	}
+	viewerID := cfg.viewerID(r)
+	// unattached media includes that of deleted chirps, waiting to be cleaned up
+	if !dbMedia.ChirpID.Valid {
+		return dbMedia.UserID == viewerID, false, nil
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), dbMedia.ChirpID.UUID)
+	if err != nil {
+		return false, false, err
+	}
+	if dbChirp.Visibility == visibilityPublic && !dbChirp.PublishAt.Valid && !dbChirp.HiddenAt.Valid {
+		return true, true, nil
+	}
+	visible, err = canView(r.Context(), cfg.dbQueries, dbChirp, viewerID)
+	return visible, false, err
+}
+
+// processedMedia is an upload after validation and re-encoding. Nothing from
+// the original file other than its pixels (and gif timing) is kept.
+type processedMedia struct {
+	kind        string
+	contentType string
+	ext         string
+	original    []byte
+	thumbnail   []byte
+	width       int
+	height      int
+}
+
+func processMedia(data []byte) (processedMedia, error) {
+	if imaging.Sniff(data) == "image/gif" {
+		g, err := imaging.DecodeGIF(data, maxGIFPixels)
+		if err != nil {
+			return processedMedia{}, err
+		}
+		original, err := imaging.EncodeGIF(g)
+		if err != nil {
+			return processedMedia{}, err
+		}
+		thumbnail, err := imaging.EncodeJPEG(imaging.Fit(g.Image[0], mediaThumbSize))
+		if err != nil {
+			return processedMedia{}, err
+		}
+		return processedMedia{kind: "gif", contentType: "image/gif", ext: "gif", original: original, thumbnail: thumbnail, width: g.Config.Width, height: g.Config.Height}, nil
+	}
+	img, contentType, err := imaging.Decode(data, maxMediaPixels)
+	if err != nil {
+		return processedMedia{}, err
+	}
+	img = imaging.Fit(imaging.Orient(img, imaging.Orientation(data)), mediaDisplaySize)
+	result := processedMedia{kind: "image", contentType: "image/jpeg", ext: "jpg", width: img.Bounds().Dx(), height: img.Bounds().Dy()}
+	// pngs stay png to keep transparency and sharp edges in screenshots
+	if contentType == "image/png" {
+		result.contentType, result.ext = "image/png", "png"
+		result.original, err = imaging.EncodePNG(img)
+	} else {
+		result.original, err = imaging.EncodeJPEG(img)
+	}
+	if err != nil {
+		return processedMedia{}, err
+	}
+	result.thumbnail, err = imaging.EncodeJPEG(imaging.Fit(img, mediaThumbSize))
+	if err != nil {
+		return processedMedia{}, err
+	}
+	return result, nil
+}
+
+// uploadMediaHandler stores an image for a chirp that hasn't been posted yet.
+// The returned id is passed in media_ids when creating the chirp; uploads
+// never attached are cleaned up after a day.
+func (cfg *apiConfig) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxMediaBytes+(1<<20))
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		respondWithText(w, 400, "Request must be multipart/form-data with a file no larger than 10MB")
+		return
+	}
+	defer file.Close()
+	altText := strings.TrimSpace(r.FormValue("alt_text"))
+	if utf8.RuneCountInString(altText) > maxAltTextLength {
+		respondWithText(w, 400, fmt.Sprintf("Alt text must be no greater than %d characters", maxAltTextLength))
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, maxMediaBytes+1))
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if len(data) > maxMediaBytes {
+		respondWithText(w, 413, "Media must be no larger than 10MB")
+		return
+	}
+	processed, err := processMedia(data)
+	if err != nil {
+		if err == imaging.ErrUnsupportedFormat {
+			respondWithText(w, 415, "Media must be a PNG, JPEG or GIF image")
+			return
+		}
+		respondWithText(w, 400, "Invalid image: "+err.Error())
+		return
+	}
+	mediaID := uuid.New()
+	prefix := fmt.Sprintf("media/%s/%s", userID, mediaID)
+	originalKey := prefix + "/original." + processed.ext
+	thumbnailKey := prefix + "/thumb.jpg"
+	err = cfg.blobStore.Put(r.Context(), originalKey, bytes.NewReader(processed.original), processed.contentType)
+	if err == nil {
+		err = cfg.blobStore.Put(r.Context(), thumbnailKey, bytes.NewReader(processed.thumbnail), "image/jpeg")
+	}
+	if err != nil {
+		fmt.Printf("Error storing media: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	dbMedia, err := cfg.dbQueries.CreateMedia(r.Context(), database.CreateMediaParams{
+		ID:           mediaID,
+		UserID:       userID,
+		Kind:         processed.kind,
+		ContentType:  processed.contentType,
+		OriginalKey:  originalKey,
+		ThumbnailKey: thumbnailKey,
+		Width:        int32(processed.width),
+		Height:       int32(processed.height),
+		SizeBytes:    int64(len(processed.original)),
+		AltText:      altText,
+	})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 201, mediaAttachment(dbMedia))
+}
+
+// updateMediaHandler changes the alt text of an upload, before or after it is attached.
+func (cfg *apiConfig) updateMediaHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		AltText string `json:"alt_text"`
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	mediaID, err := uuid.Parse(r.PathValue("mediaID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	params.AltText = strings.TrimSpace(params.AltText)
+	if utf8.RuneCountInString(params.AltText) > maxAltTextLength {
+		respondWithText(w, 400, fmt.Sprintf("Alt text must be no greater than %d characters", maxAltTextLength))
+		return
+	}
+	dbMedia, err := cfg.dbQueries.UpdateMediaAltText(r.Context(), database.UpdateMediaAltTextParams{ID: mediaID, UserID: userID, AltText: params.AltText})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, mediaAttachment(dbMedia))
+}
+
+// runMediaCleanup deletes uploads that were never attached to a chirp.
+func (cfg *apiConfig) runMediaCleanup(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		unattached, err := cfg.dbQueries.GetUnattachedMedia(ctx, database.GetUnattachedMediaParams{AgeSeconds: int32(mediaCleanupAge.Seconds()), RowLimit: mediaCleanupBatch})
+		if err != nil {
+			fmt.Printf("Error getting unattached media: %v\n", err)
+			continue
+		}
+		for _, dbMedia := range unattached {
+			// the row may have been attached since it was listed
+			n, err := cfg.dbQueries.DeleteUnattachedMedia(ctx, dbMedia.ID)
+			if err != nil || n == 0 {
+				if err != nil {
+					fmt.Printf("Error deleting media: %v\n", err)
+				}
+				continue
+			}
+			for _, key := range []string{dbMedia.OriginalKey, dbMedia.ThumbnailKey} {
+				err = cfg.blobStore.Delete(ctx, key)
+				if err != nil {
+					fmt.Printf("Error deleting media blob: %v\n", err)
+				}
+			}
+		}
+	}
+}
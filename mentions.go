@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/entities"
+)
+
+type ChirpEntities struct {
+	Hashtags []HashtagEntity `json:"hashtags"`
+	Mentions []MentionEntity `json:"mentions"`
+	URLs     []URLEntity     `json:"urls"`
+}
+
+// Entity offsets are in characters (runes) of the returned body, end exclusive.
+type HashtagEntity struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	Tag   string `json:"tag"`
+}
+
+type URLEntity struct {
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	URL   string `json:"url"`
+}
+
+type MentionEntity struct {
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+	Handle string    `json:"handle"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// chirpEntities locates hashtags, links and mentions in body. Only handles that
+// resolved to a user when the chirp was posted become mention entities.
+func chirpEntities(body string, mentioned map[string]uuid.UUID) *ChirpEntities {
+	result := &ChirpEntities{Hashtags: []HashtagEntity{}, Mentions: []MentionEntity{}, URLs: []URLEntity{}}
+	for _, tag := range entities.Hashtags(body) {
+		result.Hashtags = append(result.Hashtags, HashtagEntity{Start: tag.Start, End: tag.End, Tag: tag.Text})
+	}
+	for _, link := range entities.URLs(body) {
+		result.URLs = append(result.URLs, URLEntity{Start: link.Start, End: link.End, URL: link.Text})
+	}
+	for _, mention := range entities.Mentions(body) {
+		if userID, ok := mentioned[mention.Text]; ok {
+			result.Mentions = append(result.Mentions, MentionEntity{Start: mention.Start, End: mention.End, Handle: mention.Text, UserID: userID})
+		}
+	}
+	return result
+}
+
+func (e *ChirpEntities) mentionedUsers() map[string]uuid.UUID {
+	mentioned := map[string]uuid.UUID{}
+	if e == nil {
+		return mentioned
+	}
+	for _, mention := range e.Mentions {
+		mentioned[mention.Handle] = mention.UserID
+	}
+	return mentioned
+}
+
+// mentionChirp records the @handles in a new chirp that belong to a user and
+// returns those users. Unknown handles, and users on either side of a block
+// with the author, are ignored and stay plain text.
+func mentionChirp(ctx context.Context, q *database.Queries, dbChirp database.Chirp) ([]uuid.UUID, error) {
+	handles := entities.Unique(entities.Mentions(dbChirp.Body))
+	if len(handles) == 0 {
+		return nil, nil
+	}
+	return q.AddChirpMentions(ctx, database.AddChirpMentionsParams{ChirpID: dbChirp.ID, Handles: handles, AuthorID: dbChirp.UserID})
+}
+
+func (cfg *apiConfig) getMentions(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID]map[string]uuid.UUID, error) {
+	rows, err := cfg.dbQueries.GetChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	mentions := map[uuid.UUID]map[string]uuid.UUID{}
+	for _, row := range rows {
+		if mentions[row.ChirpID] == nil {
+			mentions[row.ChirpID] = map[string]uuid.UUID{}
+		}
+		mentions[row.ChirpID][row.Handle] = row.UserID
+	}
+	return mentions, nil
+}
@@ -0,0 +1,478 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+const (
+	maxMessageLength           = 1000
+	maxConversationTitleLength = 50
+	// including the user who starts the conversation
+	maxConversationMembers = 10
+)
+
+// DirectConversation is a private conversation between users, kept apart
+// from chirps and never shown in any chirp listing.
+type DirectConversation struct {
+	ID          uuid.UUID     `json:"id"`
+	Title       string        `json:"title,omitempty"`
+	Direct      bool          `json:"direct"`
+	Members     []UserSummary `json:"members"`
+	LastMessage *Message      `json:"last_message,omitempty"`
+	UnreadCount int32         `json:"unread_count"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       uuid.UUID `json:"sender_id"`
+	Body           string    `json:"body"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ConversationPage struct {
+	Conversations []DirectConversation `json:"conversations"`
+	NextCursor    string               `json:"next_cursor,omitempty"`
+}
+
+type MessagePage struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+func message(dbMessage database.Message) Message {
+	return Message{ID: dbMessage.ID, ConversationID: dbMessage.ConversationID, SenderID: dbMessage.SenderID, Body: dbMessage.Body, CreatedAt: dbMessage.CreatedAt}
+}
+
+// directKey identifies the one conversation two users share, whichever of
+// them starts it.
+func directKey(a, b uuid.UUID) string {
+	ids := []string{a.String(), b.String()}
+	slices.Sort(ids)
+	return strings.Join(ids, ":")
+}
+
+func directMembers(key string) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	for _, s := range strings.Split(key, ":") {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func (cfg *apiConfig) createConversationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	type parameters struct {
+		Handles []string `json:"handles"`
+		Title   string   `json:"title"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	handles := []string{}
+	for _, handle := range params.Handles {
+		handle = normalizeHandle(handle)
+		if !slices.Contains(handles, handle) {
+			handles = append(handles, handle)
+		}
+	}
+	if len(handles) == 0 {
+		respondWithText(w, 400, "A conversation needs at least one other member")
+		return
+	}
+	if len(handles)+1 > maxConversationMembers {
+		respondWithText(w, 400, fmt.Sprintf("A conversation can have at most %d members", maxConversationMembers))
+		return
+	}
+	title := strings.TrimSpace(params.Title)
+	if utf8.RuneCountInString(title) > maxConversationTitleLength {
+		respondWithText(w, 400, fmt.Sprintf("Title must be no longer than %d characters", maxConversationTitleLength))
+		return
+	}
+	if len(handles) == 1 && title != "" {
+		respondWithText(w, 400, "Only group conversations can have a title")
+		return
+	}
+	dbUsers, err := cfg.dbQueries.GetUsersByHandles(r.Context(), handles)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if len(dbUsers) != len(handles) {
+		respondWithText(w, 404, "User not found")
+		return
+	}
+	memberIDs := []uuid.UUID{userID}
+	for _, dbUser := range dbUsers {
+		if dbUser.ID == userID {
+			respondWithText(w, 400, "You cannot start a conversation with yourself")
+			return
+		}
+		memberIDs = append(memberIDs, dbUser.ID)
+	}
+	blocked, err := cfg.dbQueries.BlockExists(r.Context(), database.BlockExistsParams{UserIds: memberIDs[1:], UserID: userID})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if blocked {
+		respondWithText(w, 403, "You cannot start a conversation with a user you have blocked or who has blocked you")
+		return
+	}
+	key := sql.NullString{}
+	code := 201
+	if len(dbUsers) == 1 {
+		key = sql.NullString{String: directKey(userID, dbUsers[0].ID), Valid: true}
+		_, err := cfg.dbQueries.GetDirectConversation(r.Context(), key)
+		if err == nil {
+			code = 200
+		} else if !strings.Contains(err.Error(), "no rows in result set") {
+			fmt.Println(err.Error())
+			w.WriteHeader(500)
+			return
+		}
+	}
+	var dbConversation database.Conversation
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		dbConversation, err = q.CreateConversation(r.Context(), database.CreateConversationParams{DirectKey: key, Title: title, CreatorID: uuid.NullUUID{UUID: userID, Valid: true}})
+		if err != nil {
+			return err
+		}
+		// reopening a direct conversation only brings back the caller; the
+		// other member returns when a message arrives
+		if code == 200 {
+			memberIDs = []uuid.UUID{userID}
+		}
+		return q.AddConversationMembers(r.Context(), database.AddConversationMembersParams{ConversationID: dbConversation.ID, UserIds: memberIDs})
+	})
+	if err != nil {
+		fmt.Printf("Error creating conversation:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	conversations, err := cfg.renderConversations(r.Context(), userID, []database.Conversation{dbConversation})
+	if err != nil {
+		fmt.Printf("Error rendering conversation:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, code, conversations[0])
+}
+
+func (cfg *apiConfig) conversationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbConversations, err := cfg.dbQueries.GetConversations(r.Context(), database.GetConversationsParams{UserID: userID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+	if err != nil {
+		fmt.Printf("Error getting conversations:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	conversations, err := cfg.renderConversations(r.Context(), userID, dbConversations)
+	if err != nil {
+		fmt.Printf("Error rendering conversations:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	page := ConversationPage{Conversations: conversations}
+	if len(dbConversations) > 0 {
+		last := dbConversations[len(dbConversations)-1]
+		page.NextCursor = nextCursor(len(dbConversations), limit, pageCursor{Time: last.UpdatedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, page)
+}
+
+// renderConversations adds the active members, latest message and the
+// viewer's unread count to each conversation.
+func (cfg *apiConfig) renderConversations(ctx context.Context, userID uuid.UUID, dbConversations []database.Conversation) ([]DirectConversation, error) {
+	ids := make([]uuid.UUID, 0, len(dbConversations))
+	for _, dbConversation := range dbConversations {
+		ids = append(ids, dbConversation.ID)
+	}
+	dbMembers, err := cfg.dbQueries.GetConversationMembers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	memberIDs := make([]uuid.UUID, 0, len(dbMembers))
+	for _, dbMember := range dbMembers {
+		memberIDs = append(memberIDs, dbMember.UserID)
+	}
+	summaries, err := cfg.getUserSummaries(ctx, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+	members := map[uuid.UUID][]UserSummary{}
+	for _, dbMember := range dbMembers {
+		if summary, ok := summaries[dbMember.UserID]; ok {
+			members[dbMember.ConversationID] = append(members[dbMember.ConversationID], *summary)
+		}
+	}
+	dbLast, err := cfg.dbQueries.GetLastMessages(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	last := make(map[uuid.UUID]Message, len(dbLast))
+	for _, dbMessage := range dbLast {
+		last[dbMessage.ConversationID] = message(dbMessage)
+	}
+	unread, err := cfg.dbQueries.GetUnreadMessageCounts(ctx, database.GetUnreadMessageCountsParams{UserID: userID, ConversationIds: ids})
+	if err != nil {
+		return nil, err
+	}
+	unreadCounts := make(map[uuid.UUID]int32, len(unread))
+	for _, row := range unread {
+		unreadCounts[row.ConversationID] = row.Unread
+	}
+	conversations := make([]DirectConversation, 0, len(dbConversations))
+	for _, dbConversation := range dbConversations {
+		conversation := DirectConversation{
+			ID:          dbConversation.ID,
+			Title:       dbConversation.Title,
+			Direct:      dbConversation.DirectKey.Valid,
+			Members:     members[dbConversation.ID],
+			UnreadCount: unreadCounts[dbConversation.ID],
+			UpdatedAt:   dbConversation.UpdatedAt,
+		}
+		if conversation.Members == nil {
+			conversation.Members = []UserSummary{}
+		}
+		if m, ok := last[dbConversation.ID]; ok {
+			conversation.LastMessage = &m
+		}
+		conversations = append(conversations, conversation)
+	}
+	return conversations, nil
+}
+
+// loadMembership returns the conversation in the request path if the caller
+// is still one of its members.
+func (cfg *apiConfig) loadMembership(r *http.Request, userID uuid.UUID) (uuid.UUID, error) {
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		return uuid.Nil, &requestError{404, "Conversation not found"}
+	}
+	_, err = cfg.dbQueries.GetConversationMember(r.Context(), database.GetConversationMemberParams{ConversationID: conversationID, UserID: userID})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return uuid.Nil, &requestError{404, "Conversation not found"}
+		}
+		return uuid.Nil, err
+	}
+	return conversationID, nil
+}
+
+func (cfg *apiConfig) messagesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	conversationID, err := cfg.loadMembership(r, userID)
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	dbMessages, err := cfg.dbQueries.GetMessages(r.Context(), database.GetMessagesParams{ConversationID: conversationID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+	if err != nil {
+		fmt.Printf("Error getting messages:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	page := MessagePage{Messages: make([]Message, 0, len(dbMessages))}
+	for _, dbMessage := range dbMessages {
+		page.Messages = append(page.Messages, message(dbMessage))
+	}
+	if len(dbMessages) > 0 {
+		last := dbMessages[len(dbMessages)-1]
+		page.NextCursor = nextCursor(len(dbMessages), limit, pageCursor{Time: last.CreatedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) sendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	type parameters struct {
+		Body string `json:"body"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(params.Body) == "" {
+		respondWithText(w, 400, "A message must have a body")
+		return
+	}
+	if len(params.Body) > maxMessageLength {
+		respondWithText(w, 400, fmt.Sprintf("Message length must be no greater than %d characters", maxMessageLength))
+		return
+	}
+	conversationID, err := cfg.loadMembership(r, userID)
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	var dbMessage database.Message
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		blocked, err := q.BlockedByMember(r.Context(), database.BlockedByMemberParams{UserID: userID, ConversationID: conversationID})
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return &requestError{403, "You cannot message a user who has blocked you"}
+		}
+		dbConversation, err := q.GetConversation(r.Context(), conversationID)
+		if err != nil {
+			return err
+		}
+		// a direct conversation reappears for a member who left it once
+		// there is something new to read
+		if dbConversation.DirectKey.Valid {
+			memberIDs, err := directMembers(dbConversation.DirectKey.String)
+			if err != nil {
+				return err
+			}
+			err = q.AddConversationMembers(r.Context(), database.AddConversationMembersParams{ConversationID: conversationID, UserIds: memberIDs})
+			if err != nil {
+				return err
+			}
+		}
+		dbMessage, err = q.CreateMessage(r.Context(), database.CreateMessageParams{ConversationID: conversationID, SenderID: userID, Body: params.Body})
+		if err != nil {
+			return err
+		}
+		err = q.TouchConversation(r.Context(), conversationID)
+		if err != nil {
+			return err
+		}
+		// the sender reads their own message by sending it
+		_, err = q.MarkConversationRead(r.Context(), database.MarkConversationReadParams{ConversationID: conversationID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		dbMembers, err := q.GetConversationMembers(r.Context(), []uuid.UUID{conversationID})
+		if err != nil {
+			return err
+		}
+		topics := make([]string, 0, len(dbMembers))
+		for _, dbMember := range dbMembers {
+			topics = append(topics, messagesTopic(dbMember.UserID))
+		}
+		return publishEvent(r.Context(), q, database.CreateStreamEventParams{Type: streamEventMessage, MessageID: uuid.NullUUID{UUID: dbMessage.ID, Valid: true}, Topics: topics})
+	})
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Printf("Error sending message:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 201, message(dbMessage))
+}
+
+func (cfg *apiConfig) markConversationReadHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	n, err := cfg.dbQueries.MarkConversationRead(r.Context(), database.MarkConversationReadParams{ConversationID: conversationID, UserID: userID})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(404)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) leaveConversationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	n, err := cfg.dbQueries.LeaveConversation(r.Context(), database.LeaveConversationParams{ConversationID: conversationID, UserID: userID})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if n == 0 {
+		w.WriteHeader(404)
+		return
+	}
+	w.WriteHeader(204)
+}
@@ -0,0 +1,652 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+const (
+	reportOpen     = "open"
+	reportResolved = "resolved"
+
+	resolutionDismiss     = "dismiss"
+	resolutionWarn        = "warn"
+	resolutionRemoveChirp = "remove_chirp"
+	resolutionLimitUser   = "limit_user"
+	resolutionSuspendUser = "suspend_user"
+	resolutionBanUser     = "ban_user"
+
+	// audit trail entries that don't resolve a report
+	moderationAssign   = "assign"
+	moderationAutoHide = "auto_hide"
+
+	// distinct users with an open report on a chirp before it is hidden
+	// until a moderator looks at it
+	autoHideReporters      = 5
+	maxReportDetailsLength = 500
+	defaultSuspension      = 7 * 24 * time.Hour
+	maxSuspension          = 365 * 24 * time.Hour
+)
+
+// the account state each resolution against a user puts them in
+var resolutionStates = map[string]string{
+	resolutionLimitUser:   accountLimited,
+	resolutionSuspendUser: accountSuspended,
+	resolutionBanUser:     accountBanned,
+}
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "self_harm", "sexual", "misinformation", "impersonation", "other"}
+
+// in order of severity, so moderators can respond in proportion
+var resolutions = []string{resolutionDismiss, resolutionWarn, resolutionRemoveChirp, resolutionLimitUser, resolutionSuspendUser, resolutionBanUser}
+
+type Report struct {
+	ID          uuid.UUID    `json:"id"`
+	Reason      string       `json:"reason"`
+	Details     string       `json:"details,omitempty"`
+	Status      string       `json:"status"`
+	Resolution  string       `json:"resolution,omitempty"`
+	Reporter    *UserSummary `json:"reporter,omitempty"`
+	User        *UserSummary `json:"user,omitempty"`
+	Chirp       *Chirp       `json:"chirp,omitempty"`
+	OpenReports int32        `json:"open_reports,omitempty"`
+	AssignedTo  *UserSummary `json:"assigned_to,omitempty"`
+	ResolvedBy  *UserSummary `json:"resolved_by,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	ResolvedAt  *time.Time   `json:"resolved_at,omitempty"`
+}
+
+type ReportPage struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+type ModerationAction struct {
+	ID            uuid.UUID    `json:"id"`
+	Moderator     *UserSummary `json:"moderator,omitempty"`
+	ReportID      *uuid.UUID   `json:"report_id,omitempty"`
+	Action        string       `json:"action"`
+	TargetUserID  *uuid.UUID   `json:"target_user_id,omitempty"`
+	TargetChirpID *uuid.UUID   `json:"target_chirp_id,omitempty"`
+	Note          string       `json:"note,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type ModerationLog struct {
+	Actions    []ModerationAction `json:"actions"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func decodeReport(r *http.Request) (string, string, error) {
+	type parameters struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	params := parameters{}
+	err := json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		return "", "", errors.New("Invalid request body")
+	}
+	if !slices.Contains(reportReasons, params.Reason) {
+		return "", "", fmt.Errorf("reason must be one of %s", strings.Join(reportReasons, ", "))
+	}
+	details := strings.TrimSpace(params.Details)
+	if len(details) > maxReportDetailsLength {
+		return "", "", fmt.Errorf("Details must be no greater than %d characters", maxReportDetailsLength)
+	}
+	return params.Reason, details, nil
+}
+
+func (cfg *apiConfig) reportChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	reason, details, err := decodeReport(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	visible, err := canView(r.Context(), cfg.dbQueries, dbChirp, userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbChirp.DeletedAt.Valid || !visible {
+		w.WriteHeader(404)
+		return
+	}
+	if dbChirp.UserID == userID {
+		respondWithText(w, 400, "You cannot report your own chirp")
+		return
+	}
+	var dbReport database.Report
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		dbReport, err = q.CreateReport(r.Context(), database.CreateReportParams{ReporterID: userID, UserID: dbChirp.UserID, ChirpID: uuid.NullUUID{UUID: chirpID, Valid: true}, Reason: reason, Details: details})
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return &requestError{409, "You have already reported this chirp"}
+			}
+			return err
+		}
+		return autoHide(r.Context(), q, dbChirp, dbReport.ID)
+	})
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Printf("Error reporting chirp:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 201, Report{ID: dbReport.ID, Reason: dbReport.Reason, Details: dbReport.Details, Status: dbReport.Status, CreatedAt: dbReport.CreatedAt})
+}
+
+// autoHide takes a chirp out of every read path once enough people have
+// reported it, until a moderator dismisses or acts on the reports.
+func autoHide(ctx context.Context, q *database.Queries, dbChirp database.Chirp, reportID uuid.UUID) error {
+	reporters, err := q.CountChirpReporters(ctx, uuid.NullUUID{UUID: dbChirp.ID, Valid: true})
+	if err != nil || reporters < autoHideReporters {
+		return err
+	}
+	n, err := q.HideChirp(ctx, dbChirp.ID)
+	if err != nil || n == 0 {
+		return err
+	}
+	err = q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		ReportID:      uuid.NullUUID{UUID: reportID, Valid: true},
+		Action:        moderationAutoHide,
+		TargetUserID:  uuid.NullUUID{UUID: dbChirp.UserID, Valid: true},
+		TargetChirpID: uuid.NullUUID{UUID: dbChirp.ID, Valid: true},
+		Note:          fmt.Sprintf("Hidden after reports from %d users", reporters),
+	})
+	if err != nil {
+		return err
+	}
+	// live clients drop it as if it were deleted
+	return publishChirpEvent(ctx, q, streamEventDeleted, dbChirp)
+}
+
+func (cfg *apiConfig) reportUserHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	reason, details, err := decodeReport(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbUser.ID == userID {
+		respondWithText(w, 400, "You cannot report yourself")
+		return
+	}
+	dbReport, err := cfg.dbQueries.CreateReport(r.Context(), database.CreateReportParams{ReporterID: userID, UserID: dbUser.ID, Reason: reason, Details: details})
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			respondWithText(w, 409, "You have already reported this user")
+			return
+		}
+		fmt.Printf("Error reporting user:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 201, Report{ID: dbReport.ID, Reason: dbReport.Reason, Details: dbReport.Details, Status: dbReport.Status, CreatedAt: dbReport.CreatedAt})
+}
+
+// authenticateModerator responds 401 or 403 itself and returns false unless
+// the caller is a moderator.
+func (cfg *apiConfig) authenticateModerator(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return uuid.Nil, false
+	}
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return uuid.Nil, false
+	}
+	if !dbUser.IsModerator || lockedOut(dbUser) != "" {
+		w.WriteHeader(403)
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// reportsHandler serves the moderator queue. status is open (the default) or
+// resolved, and assigned is "me" or "none" to narrow it down.
+func (cfg *apiConfig) reportsHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	params := database.GetReportsParams{Status: reportOpen, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit}
+	switch status := r.URL.Query().Get("status"); status {
+	case "", reportOpen:
+	case reportResolved:
+		params.Status = reportResolved
+	default:
+		respondWithText(w, 400, "status must be open or resolved")
+		return
+	}
+	switch r.URL.Query().Get("assigned") {
+	case "":
+	case "me":
+		params.AssignedTo = uuid.NullUUID{UUID: moderatorID, Valid: true}
+	case "none":
+		params.Unassigned = true
+	default:
+		respondWithText(w, 400, "assigned must be me or none")
+		return
+	}
+	dbReports, err := cfg.dbQueries.GetReports(r.Context(), params)
+	if err != nil {
+		fmt.Printf("Error getting reports:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	reports, err := cfg.renderReports(r.Context(), dbReports)
+	if err != nil {
+		fmt.Printf("Error rendering reports:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	page := ReportPage{Reports: reports}
+	if len(dbReports) > 0 {
+		last := dbReports[len(dbReports)-1]
+		page.NextCursor = nextCursor(len(dbReports), limit, pageCursor{Time: last.CreatedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, page)
+}
+
+// renderReports loads the people and chirps involved in each report.
+// Moderators see reported chirps even while they are hidden.
+func (cfg *apiConfig) renderReports(ctx context.Context, dbReports []database.Report) ([]Report, error) {
+	userIDs := []uuid.UUID{}
+	chirpIDs := []uuid.UUID{}
+	for _, dbReport := range dbReports {
+		userIDs = append(userIDs, dbReport.ReporterID, dbReport.UserID)
+		if dbReport.AssignedTo.Valid {
+			userIDs = append(userIDs, dbReport.AssignedTo.UUID)
+		}
+		if dbReport.ResolvedBy.Valid {
+			userIDs = append(userIDs, dbReport.ResolvedBy.UUID)
+		}
+		if dbReport.ChirpID.Valid {
+			chirpIDs = append(chirpIDs, dbReport.ChirpID.UUID)
+		}
+	}
+	summaries, err := cfg.getUserSummaries(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	chirps := map[uuid.UUID]*Chirp{}
+	counts := map[uuid.UUID]int32{}
+	if len(chirpIDs) > 0 {
+		dbChirps, err := cfg.dbQueries.GetChirpsByIDs(ctx, chirpIDs)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := cfg.renderChirps(ctx, uuid.Nil, dbChirps)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rendered {
+			chirps[rendered[i].ID] = &rendered[i]
+		}
+		rows, err := cfg.dbQueries.GetReportCounts(ctx, chirpIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			counts[row.ChirpID.UUID] = row.OpenReports
+		}
+	}
+	reports := make([]Report, 0, len(dbReports))
+	for _, dbReport := range dbReports {
+		report := Report{
+			ID:         dbReport.ID,
+			Reason:     dbReport.Reason,
+			Details:    dbReport.Details,
+			Status:     dbReport.Status,
+			Resolution: dbReport.Resolution.String,
+			Reporter:   summaries[dbReport.ReporterID],
+			User:       summaries[dbReport.UserID],
+			CreatedAt:  dbReport.CreatedAt,
+		}
+		if dbReport.ChirpID.Valid {
+			report.Chirp = chirps[dbReport.ChirpID.UUID]
+			report.OpenReports = counts[dbReport.ChirpID.UUID]
+		}
+		if dbReport.AssignedTo.Valid {
+			report.AssignedTo = summaries[dbReport.AssignedTo.UUID]
+		}
+		if dbReport.ResolvedBy.Valid {
+			report.ResolvedBy = summaries[dbReport.ResolvedBy.UUID]
+		}
+		if dbReport.ResolvedAt.Valid {
+			report.ResolvedAt = &dbReport.ResolvedAt.Time
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// assignReportHandler gives an open report to the calling moderator, or to
+// the moderator named by handle.
+func (cfg *apiConfig) assignReportHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	type parameters struct {
+		Handle string `json:"handle"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil && err.Error() != "EOF" {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	assignee, err := cfg.dbQueries.GetUserByID(r.Context(), moderatorID)
+	if params.Handle != "" {
+		assignee, err = cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(params.Handle))
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			respondWithText(w, 404, "User not found")
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if !assignee.IsModerator {
+		respondWithText(w, 400, "Reports can only be assigned to moderators")
+		return
+	}
+	var dbReport database.Report
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		dbReport, err = q.AssignReport(r.Context(), database.AssignReportParams{ID: reportID, AssignedTo: uuid.NullUUID{UUID: assignee.ID, Valid: true}})
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return &requestError{404, "Open report not found"}
+			}
+			return err
+		}
+		return q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID:   uuid.NullUUID{UUID: moderatorID, Valid: true},
+			ReportID:      uuid.NullUUID{UUID: reportID, Valid: true},
+			Action:        moderationAssign,
+			TargetUserID:  uuid.NullUUID{UUID: dbReport.UserID, Valid: true},
+			TargetChirpID: dbReport.ChirpID,
+			Note:          "Assigned to @" + assignee.Handle.String,
+		})
+	})
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Printf("Error assigning report:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	cfg.respondWithReport(w, r.Context(), dbReport)
+}
+
+// resolveReportHandler closes a report, and every other open report about
+// the same target, with one of the resolution actions:
+//   - dismiss brings back a chirp that was hidden by report volume
+//   - warn sends the reported user a warning notification
+//   - remove_chirp deletes the reported chirp
+//   - limit_user hides everything the reported user posts from everyone else
+//   - suspend_user locks the reported user out for suspend_hours (a week by
+//     default)
+//   - ban_user locks the reported user out for good
+func (cfg *apiConfig) resolveReportHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	type parameters struct {
+		Action       string `json:"action"`
+		Note         string `json:"note"`
+		SuspendHours int    `json:"suspend_hours"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	if !slices.Contains(resolutions, params.Action) {
+		respondWithText(w, 400, fmt.Sprintf("action must be one of %s", strings.Join(resolutions, ", ")))
+		return
+	}
+	suspension, err := suspensionLength(params.SuspendHours)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbReport, err := cfg.dbQueries.GetReport(r.Context(), reportID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbReport.Status != reportOpen {
+		respondWithText(w, 409, "The report has already been resolved")
+		return
+	}
+	if params.Action == resolutionRemoveChirp && !dbReport.ChirpID.Valid {
+		respondWithText(w, 400, "Only chirp reports can be resolved by removing the chirp")
+		return
+	}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		switch params.Action {
+		case resolutionDismiss:
+			if dbReport.ChirpID.Valid {
+				err := q.UnhideChirp(r.Context(), dbReport.ChirpID.UUID)
+				if err != nil {
+					return err
+				}
+			}
+		case resolutionRemoveChirp:
+			dbChirp, err := q.GetChirp(r.Context(), dbReport.ChirpID.UUID)
+			if err != nil {
+				return err
+			}
+			if !dbChirp.DeletedAt.Valid {
+				err = removeChirp(r.Context(), q, dbChirp)
+				if err != nil {
+					return err
+				}
+			}
+		case resolutionLimitUser, resolutionSuspendUser, resolutionBanUser:
+			_, err := setAccountState(r.Context(), q, dbReport.UserID, resolutionStates[params.Action], time.Now().UTC().Add(suspension))
+			if err != nil {
+				return err
+			}
+		case resolutionWarn:
+			notificationID, err := q.UpsertNotification(r.Context(), database.UpsertNotificationParams{UserID: dbReport.UserID, Type: notificationWarning, ChirpID: dbReport.ChirpID, GroupKey: notificationWarning + ":" + reportID.String()})
+			if err != nil {
+				return err
+			}
+			err = publishEvent(r.Context(), q, database.CreateStreamEventParams{Type: streamEventNotification, NotificationID: uuid.NullUUID{UUID: notificationID, Valid: true}, Topics: []string{notificationsTopic(dbReport.UserID)}})
+			if err != nil {
+				return err
+			}
+		}
+		_, err := q.ResolveReports(r.Context(), database.ResolveReportsParams{
+			Resolution: sql.NullString{String: params.Action, Valid: true},
+			ResolvedBy: uuid.NullUUID{UUID: moderatorID, Valid: true},
+			ID:         reportID,
+			ChirpID:    dbReport.ChirpID,
+			UserID:     dbReport.UserID,
+		})
+		if err != nil {
+			return err
+		}
+		return q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID:   uuid.NullUUID{UUID: moderatorID, Valid: true},
+			ReportID:      uuid.NullUUID{UUID: reportID, Valid: true},
+			Action:        params.Action,
+			TargetUserID:  uuid.NullUUID{UUID: dbReport.UserID, Valid: true},
+			TargetChirpID: dbReport.ChirpID,
+			Note:          strings.TrimSpace(params.Note),
+		})
+	})
+	if err != nil {
+		fmt.Printf("Error resolving report:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	dbReport, err = cfg.dbQueries.GetReport(r.Context(), reportID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	cfg.respondWithReport(w, r.Context(), dbReport)
+}
+
+func (cfg *apiConfig) respondWithReport(w http.ResponseWriter, ctx context.Context, dbReport database.Report) {
+	reports, err := cfg.renderReports(ctx, []database.Report{dbReport})
+	if err != nil {
+		fmt.Printf("Error rendering report:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, reports[0])
+}
+
+// moderationLogHandler serves the audit trail of moderator decisions,
+// optionally for one user.
+func (cfg *apiConfig) moderationLogHandler(w http.ResponseWriter, r *http.Request) {
+	_, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	params := database.GetModerationActionsParams{BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit}
+	if handle := r.URL.Query().Get("user"); handle != "" {
+		dbUser, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(handle))
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				respondWithText(w, 404, "User not found")
+				return
+			}
+			fmt.Println(err.Error())
+			w.WriteHeader(500)
+			return
+		}
+		params.TargetUserID = uuid.NullUUID{UUID: dbUser.ID, Valid: true}
+	}
+	dbActions, err := cfg.dbQueries.GetModerationActions(r.Context(), params)
+	if err != nil {
+		fmt.Printf("Error getting moderation log:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	moderatorIDs := []uuid.UUID{}
+	for _, dbAction := range dbActions {
+		if dbAction.ModeratorID.Valid {
+			moderatorIDs = append(moderatorIDs, dbAction.ModeratorID.UUID)
+		}
+	}
+	summaries, err := cfg.getUserSummaries(r.Context(), moderatorIDs)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	log := ModerationLog{Actions: make([]ModerationAction, 0, len(dbActions))}
+	for _, dbAction := range dbActions {
+		action := ModerationAction{ID: dbAction.ID, Action: dbAction.Action, Note: dbAction.Note, CreatedAt: dbAction.CreatedAt}
+		if dbAction.ModeratorID.Valid {
+			action.Moderator = summaries[dbAction.ModeratorID.UUID]
+		}
+		if dbAction.ReportID.Valid {
+			action.ReportID = &dbAction.ReportID.UUID
+		}
+		if dbAction.TargetUserID.Valid {
+			action.TargetUserID = &dbAction.TargetUserID.UUID
+		}
+		if dbAction.TargetChirpID.Valid {
+			action.TargetChirpID = &dbAction.TargetChirpID.UUID
+		}
+		log.Actions = append(log.Actions, action)
+	}
+	if len(dbActions) > 0 {
+		last := dbActions[len(dbActions)-1]
+		log.NextCursor = nextCursor(len(dbActions), limit, pageCursor{Time: last.CreatedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, log)
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+const (
+	notificationMention = "mention"
+	notificationReply   = "reply"
+	notificationQuote   = "quote"
+	notificationLike    = "like"
+	notificationRechirp = "rechirp"
+	notificationFollow  = "follow"
+	// sent by moderators; deliberately missing from notificationTypes so it
+	// can't be turned off
+	notificationWarning = "warning"
+)
+
+var notificationTypes = []string{notificationMention, notificationReply, notificationQuote, notificationLike, notificationRechirp, notificationFollow}
+
+// how many of the most recent actors are listed on a collapsed notification
+const notificationActorsShown = 3
+
+type Notification struct {
+	ID         uuid.UUID     `json:"id"`
+	Type       string        `json:"type"`
+	Actors     []UserSummary `json:"actors"`
+	ActorCount int32         `json:"actor_count"`
+	Chirp      *Chirp        `json:"chirp,omitempty"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	Read       bool          `json:"read"`
+}
+
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	NextCursor    string         `json:"next_cursor,omitempty"`
+}
+
+// notify records that actor did something to recipient. Events with the same
+// type and chirp collapse into the recipient's unread notification for it, so
+// likes, rechirps and follows group up while every mention, reply and quote
+// (each a chirp of its own) stands alone. Nothing is recorded for a user's own
+// activity, for types the recipient has turned off, or from users the
+// recipient has blocked or muted.
+func (cfg *apiConfig) notify(ctx context.Context, q *database.Queries, recipient, actor uuid.UUID, kind string, chirpID uuid.UUID) error {
+	if recipient == actor {
+		return nil
+	}
+	enabled, err := q.NotificationEnabled(ctx, database.NotificationEnabledParams{UserID: recipient, Type: kind, ActorID: actor})
+	if err != nil || !enabled {
+		return err
+	}
+	groupKey := kind
+	if chirpID != uuid.Nil {
+		groupKey += ":" + chirpID.String()
+	}
+	notificationID, err := q.UpsertNotification(ctx, database.UpsertNotificationParams{UserID: recipient, Type: kind, ChirpID: uuid.NullUUID{UUID: chirpID, Valid: chirpID != uuid.Nil}, GroupKey: groupKey})
+	if err != nil {
+		return err
+	}
+	// an actor repeating an event (like, unlike, like) only counts once
+	n, err := q.AddNotificationActor(ctx, database.AddNotificationActorParams{NotificationID: notificationID, ActorID: actor})
+	if err != nil || n == 0 {
+		return err
+	}
+	err = q.IncrementNotificationActorCount(ctx, notificationID)
+	if err != nil {
+		return err
+	}
+	return publishEvent(ctx, q, database.CreateStreamEventParams{Type: streamEventNotification, NotificationID: uuid.NullUUID{UUID: notificationID, Valid: true}, Topics: []string{notificationsTopic(recipient)}})
+}
+
+func (cfg *apiConfig) notificationsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbNotifications, err := cfg.dbQueries.GetNotifications(r.Context(), database.GetNotificationsParams{UserID: userID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+	if err != nil {
+		fmt.Printf("Error getting notifications: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	notifications, err := cfg.renderNotifications(r.Context(), userID, dbNotifications)
+	if err != nil {
+		fmt.Printf("Error rendering notifications: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	page := NotificationPage{Notifications: notifications}
+	if len(dbNotifications) > 0 {
+		last := dbNotifications[len(dbNotifications)-1]
+		page.NextCursor = nextCursor(len(dbNotifications), limit, pageCursor{Time: last.UpdatedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) renderNotifications(ctx context.Context, userID uuid.UUID, dbNotifications []database.Notification) ([]Notification, error) {
+	notificationIDs := make([]uuid.UUID, 0, len(dbNotifications))
+	chirpIDs := []uuid.UUID{}
+	for _, dbNotification := range dbNotifications {
+		notificationIDs = append(notificationIDs, dbNotification.ID)
+		if dbNotification.ChirpID.Valid {
+			chirpIDs = append(chirpIDs, dbNotification.ChirpID.UUID)
+		}
+	}
+	actorRows, err := cfg.dbQueries.GetNotificationActors(ctx, database.GetNotificationActorsParams{NotificationIds: notificationIDs, PerNotification: notificationActorsShown})
+	if err != nil {
+		return nil, err
+	}
+	actorIDs := make([]uuid.UUID, 0, len(actorRows))
+	for _, row := range actorRows {
+		actorIDs = append(actorIDs, row.ActorID)
+	}
+	summaries, err := cfg.getUserSummaries(ctx, actorIDs)
+	if err != nil {
+		return nil, err
+	}
+	actors := make(map[uuid.UUID][]UserSummary, len(dbNotifications))
+	for _, row := range actorRows {
+		if summary, ok := summaries[row.ActorID]; ok {
+			actors[row.NotificationID] = append(actors[row.NotificationID], *summary)
+		}
+	}
+	// notifications about a chirp the user can no longer see, or that their
+	// muted words hide, are left out
+	chirps := map[uuid.UUID]*Chirp{}
+	mutedWords, err := cfg.mutedWordFilter(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chirpIDs) > 0 {
+		dbChirps, err := cfg.dbQueries.GetVisibleChirpsByIDs(ctx, database.GetVisibleChirpsByIDsParams{Ids: chirpIDs, ViewerID: userID})
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := cfg.renderChirps(ctx, userID, dbChirps)
+		if err != nil {
+			return nil, err
+		}
+		rendered = filterChirps(mutedWords, userID, rendered)
+		for i := range rendered {
+			chirps[rendered[i].ID] = &rendered[i]
+		}
+	}
+	notifications := make([]Notification, 0, len(dbNotifications))
+	for _, dbNotification := range dbNotifications {
+		notification := Notification{ID: dbNotification.ID, Type: dbNotification.Type, Actors: actors[dbNotification.ID], ActorCount: dbNotification.ActorCount, UpdatedAt: dbNotification.UpdatedAt, Read: dbNotification.ReadAt.Valid}
+		if notification.Actors == nil {
+			notification.Actors = []UserSummary{}
+		}
+		if dbNotification.ChirpID.Valid {
+			chirp, ok := chirps[dbNotification.ChirpID.UUID]
+			if !ok {
+				continue
+			}
+			notification.Chirp = chirp
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+func (cfg *apiConfig) unreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	count, err := cfg.dbQueries.CountUnreadNotifications(r.Context(), userID)
+	if err != nil {
+		fmt.Printf("Error counting notifications: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, map[string]int64{"unread_count": count})
+}
+
+// markReadHandler marks the listed notifications as read, or all of them when
+// no ids are given.
+func (cfg *apiConfig) markReadHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		IDs []uuid.UUID `json:"ids"`
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	params := parameters{}
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&params)
+		if err != nil {
+			respondWithText(w, 400, "Invalid request body")
+			return
+		}
+	}
+	if len(params.IDs) == 0 {
+		_, err = cfg.dbQueries.MarkAllNotificationsRead(r.Context(), userID)
+	} else {
+		_, err = cfg.dbQueries.MarkNotificationsRead(r.Context(), database.MarkNotificationsReadParams{UserID: userID, Ids: params.IDs})
+	}
+	if err != nil {
+		fmt.Printf("Error marking notifications read: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+func (cfg *apiConfig) getNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	cfg.respondWithPreferences(w, r.Context(), userID)
+}
+
+// updateNotificationPreferencesHandler takes a map of notification type to
+// whether it should be received; types left out keep their current setting.
+func (cfg *apiConfig) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	params := map[string]bool{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	for kind := range params {
+		if !isNotificationType(kind) {
+			respondWithText(w, 400, "Unknown notification type: "+kind)
+			return
+		}
+	}
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		for kind, enabled := range params {
+			err := q.SetNotificationPreference(r.Context(), database.SetNotificationPreferenceParams{UserID: userID, Type: kind, Enabled: enabled})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("Error updating notification preferences: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	cfg.respondWithPreferences(w, r.Context(), userID)
+}
+
+func (cfg *apiConfig) respondWithPreferences(w http.ResponseWriter, ctx context.Context, userID uuid.UUID) {
+	rows, err := cfg.dbQueries.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		fmt.Printf("Error getting notification preferences: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+	// every type is on unless the user turned it off
+	preferences := make(map[string]bool, len(notificationTypes))
+	for _, kind := range notificationTypes {
+		preferences[kind] = true
+	}
+	for _, row := range rows {
+		preferences[row.Type] = row.Enabled
+	}
+	respondWithJSON(w, 200, preferences)
+}
+
+func isNotificationType(kind string) bool {
+	for _, t := range notificationTypes {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor points just past the last item of the previous page. Lists are
+// ordered newest first by (time, id) so the cursor stays stable as new rows arrive.
+type pageCursor struct {
+	Time time.Time
+	ID   uuid.UUID
+}
+
+func firstPage() pageCursor {
+	return pageCursor{Time: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC), ID: uuid.Max}
+}
+
+func (c pageCursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()))
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("Invalid cursor")
+	}
+	parts := strings.SplitN(string(dat), "|", 2)
+	if len(parts) != 2 {
+		return pageCursor{}, fmt.Errorf("Invalid cursor")
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("Invalid cursor")
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("Invalid cursor")
+	}
+	return pageCursor{Time: t, ID: id}, nil
+}
+
+// parsePage reads the cursor and limit query parameters.
+func parsePage(r *http.Request) (pageCursor, int32, error) {
+	cursor := firstPage()
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		c, err := decodeCursor(s)
+		if err != nil {
+			return pageCursor{}, 0, err
+		}
+		cursor = c
+	}
+	limit := defaultPageSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		l, err := strconv.Atoi(s)
+		if err != nil || l < 1 {
+			return pageCursor{}, 0, fmt.Errorf("Invalid limit")
+		}
+		limit = min(l, maxPageSize)
+	}
+	return cursor, int32(limit), nil
+}
+
+// nextCursor returns the cursor for the following page, or "" when the page
+// wasn't full and there is nothing more to fetch.
+func nextCursor(count int, limit int32, last pageCursor) string {
+	if count < int(limit) {
+		return ""
+	}
+	return last.encode()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/polls"
+)
+
+const pollCloseInterval = 30 * time.Second
+
+type pollInput struct {
+	Options         []string `json:"options"`
+	DurationMinutes int      `json:"duration_minutes"`
+}
+
+type PollOption struct {
+	Text string `json:"text"`
+	// hidden until the viewer has voted or the poll has closed
+	Votes *int32 `json:"votes,omitempty"`
+}
+
+type Poll struct {
+	Options     []PollOption `json:"options"`
+	TotalVotes  int32        `json:"total_votes"`
+	ClosesAt    *time.Time   `json:"closes_at,omitempty"`
+	Closed      bool         `json:"closed"`
+	VotedOption *int32       `json:"voted_option,omitempty"`
+}
+
+// validatePoll trims the options and checks the poll, returning its duration.
+func validatePoll(poll *pollInput) (time.Duration, error) {
+	duration, err := polls.Validate(poll.Options, poll.DurationMinutes)
+	if err != nil {
+		return 0, &requestError{400, err.Error()}
+	}
+	return duration, nil
+}
+
+func pollClosed(dbPoll database.Poll) bool {
+	return dbPoll.ClosedAt.Valid || (dbPoll.ClosesAt.Valid && !time.Now().Before(dbPoll.ClosesAt.Time))
+}
+
+// getPolls renders the polls on chirps for a viewer, or uuid.Nil for anonymous
+// requests, who never see results before a poll closes.
+func (cfg *apiConfig) getPolls(ctx context.Context, viewerID uuid.UUID, chirpIDs []uuid.UUID) (map[uuid.UUID]*Poll, error) {
+	dbPolls, err := cfg.dbQueries.GetPolls(ctx, chirpIDs)
+	if err != nil || len(dbPolls) == 0 {
+		return nil, err
+	}
+	pollIDs := make([]uuid.UUID, 0, len(dbPolls))
+	for _, dbPoll := range dbPolls {
+		pollIDs = append(pollIDs, dbPoll.ChirpID)
+	}
+	options, err := cfg.dbQueries.GetPollOptions(ctx, pollIDs)
+	if err != nil {
+		return nil, err
+	}
+	votes := map[uuid.UUID]int32{}
+	if viewerID != uuid.Nil {
+		rows, err := cfg.dbQueries.GetPollVotes(ctx, database.GetPollVotesParams{UserID: viewerID, ChirpIds: pollIDs})
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			votes[row.ChirpID] = row.Position
+		}
+	}
+	polls := make(map[uuid.UUID]*Poll, len(dbPolls))
+	for _, dbPoll := range dbPolls {
+		poll := &Poll{Options: []PollOption{}, TotalVotes: dbPoll.TotalVotes, Closed: pollClosed(dbPoll)}
+		if dbPoll.ClosesAt.Valid {
+			poll.ClosesAt = &dbPoll.ClosesAt.Time
+		}
+		if position, ok := votes[dbPoll.ChirpID]; ok {
+			poll.VotedOption = &position
+		}
+		polls[dbPoll.ChirpID] = poll
+	}
+	for _, option := range options {
+		poll := polls[option.ChirpID]
+		rendered := PollOption{Text: option.Text}
+		if poll.Closed || poll.VotedOption != nil {
+			rendered.Votes = &option.VoteCount
+		}
+		poll.Options = append(poll.Options, rendered)
+	}
+	return polls, nil
+}
+
+func (cfg *apiConfig) votePollHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Option *int32 `json:"option"`
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil || params.Option == nil {
+		respondWithText(w, 400, "An option is required")
+		return
+	}
+	err = cfg.castVote(r.Context(), userID, chirpID, *params.Option)
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Printf("Error voting:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	polls, err := cfg.getPolls(r.Context(), userID, []uuid.UUID{chirpID})
+	if err != nil {
+		fmt.Printf("Error rendering poll:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 201, polls[chirpID])
+}
+
+func (cfg *apiConfig) castVote(ctx context.Context, userID, chirpID uuid.UUID, option int32) error {
+	dbChirp, err := cfg.dbQueries.GetChirp(ctx, chirpID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return &requestError{404, "Chirp not found"}
+		}
+		return err
+	}
+	visible, err := canView(ctx, cfg.dbQueries, dbChirp, userID)
+	if err != nil {
+		return err
+	}
+	if dbChirp.DeletedAt.Valid || dbChirp.PublishAt.Valid || !visible {
+		return &requestError{404, "Chirp not found"}
+	}
+	dbPoll, err := cfg.dbQueries.GetPoll(ctx, chirpID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			return &requestError{404, "This chirp has no poll"}
+		}
+		return err
+	}
+	if pollClosed(dbPoll) {
+		return &requestError{409, "This poll has closed"}
+	}
+	options, err := cfg.dbQueries.GetPollOptions(ctx, []uuid.UUID{chirpID})
+	if err != nil {
+		return err
+	}
+	if option < 0 || int(option) >= len(options) {
+		return &requestError{400, "Invalid option"}
+	}
+	// the tallies only move when the vote row was inserted
+	return cfg.inTx(ctx, func(q *database.Queries) error {
+		n, err := q.CastPollVote(ctx, database.CastPollVoteParams{ChirpID: chirpID, UserID: userID, Position: option})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &requestError{409, "You have already voted in this poll"}
+		}
+		return q.CountPollVote(ctx, database.CountPollVoteParams{ChirpID: chirpID, Position: option})
+	})
+}
+
+// runPollCloser marks polls closed once their time is up. Rendering already
+// treats an expired poll as closed, so this only has to catch up eventually,
+// and running it on several instances is harmless.
+func (cfg *apiConfig) runPollCloser(ctx context.Context) {
+	ticker := time.NewTicker(pollCloseInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		_, err := cfg.dbQueries.ClosePolls(ctx)
+		if err != nil {
+			fmt.Printf("Error closing polls: %v\n", err)
+		}
+	}
+}
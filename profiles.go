@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/imaging"
+)
+
+const (
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarBytes       = 5 << 20
+	maxAvatarPixels      = 4096 * 4096
+)
+
+var handlePattern = regexp.MustCompile(`^[a-z0-9_]{3,15}$`)
+
+// avatar size name -> edge length in pixels
+var avatarSizes = map[string]int{
+	"small":  48,
+	"normal": 128,
+	"large":  400,
+}
+
+type UserSummary struct {
+	ID          uuid.UUID `json:"id"`
+	Handle      string    `json:"handle,omitempty"`
+	DisplayName string    `json:"display_name"`
+	AvatarURL   string    `json:"avatar_url,omitempty"`
+}
+
+type Profile struct {
+	ID          uuid.UUID         `json:"id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	Handle      string            `json:"handle,omitempty"`
+	DisplayName string            `json:"display_name"`
+	Bio         string            `json:"bio"`
+	Avatar      map[string]string `json:"avatar,omitempty"`
+	Followers   int64             `json:"follower_count"`
+	Following   int64             `json:"following_count"`
+}
+
+func normalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(handle), "@"))
+}
+
+func validateProfileFields(handle, displayName, bio string) error {
+	if handle != "" && !handlePattern.MatchString(handle) {
+		return fmt.Errorf("Handle must be 3-15 characters of letters, numbers or underscores")
+	}
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return fmt.Errorf("Display name must be no greater than %d characters", maxDisplayNameLength)
+	}
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return fmt.Errorf("Bio must be no greater than %d characters", maxBioLength)
+	}
+	return nil
+}
+
+func avatarURLs(avatarKey sql.NullString) map[string]string {
+	if !avatarKey.Valid {
+		return nil
+	}
+	urls := make(map[string]string, len(avatarSizes))
+	for name := range avatarSizes {
+		urls[name] = mediaURL(avatarKey.String + "/" + name + ".jpg")
+	}
+	return urls
+}
+
+func (cfg *apiConfig) loadProfile(ctx context.Context, dbUser database.User) (Profile, error) {
+	counts, err := cfg.dbQueries.GetFollowCounts(ctx, dbUser.ID)
+	if err != nil {
+		return Profile{}, err
+	}
+	return Profile{ID: dbUser.ID, CreatedAt: dbUser.CreatedAt.Time, Handle: dbUser.Handle.String, DisplayName: dbUser.DisplayName, Bio: dbUser.Bio, Avatar: avatarURLs(dbUser.AvatarKey), Followers: counts.FollowerCount, Following: counts.FollowingCount}, nil
+}
+
+func (cfg *apiConfig) respondWithProfile(w http.ResponseWriter, ctx context.Context, dbUser database.User) {
+	profile, err := cfg.loadProfile(ctx, dbUser)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, profile)
+}
+
+func (cfg *apiConfig) getUserSummaries(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*UserSummary, error) {
+	rows, err := cfg.dbQueries.GetUserSummaries(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make(map[uuid.UUID]*UserSummary, len(rows))
+	for _, row := range rows {
+		summaries[row.ID] = userSummary(row.ID, row.Handle, row.DisplayName, row.AvatarKey)
+	}
+	return summaries, nil
+}
+
+func userSummary(id uuid.UUID, handle sql.NullString, displayName string, avatarKey sql.NullString) *UserSummary {
+	summary := &UserSummary{ID: id, Handle: handle.String, DisplayName: displayName}
+	if avatarKey.Valid {
+		summary.AvatarURL = mediaURL(avatarKey.String + "/normal.jpg")
+	}
+	return summary
+}
+
+func (cfg *apiConfig) getProfileHandler(w http.ResponseWriter, r *http.Request) {
+	dbUser, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(r.PathValue("handle")))
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	cfg.respondWithProfile(w, r.Context(), dbUser)
+}
+
+func (cfg *apiConfig) updateProfileHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	params := parameters{}
+	err = decoder.Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	// fields left out of the request keep their current value
+	update := database.UpdateUserProfileParams{ID: userID, Handle: dbUser.Handle, DisplayName: dbUser.DisplayName, Bio: dbUser.Bio}
+	if params.Handle != nil {
+		handle := normalizeHandle(*params.Handle)
+		update.Handle = sql.NullString{String: handle, Valid: handle != ""}
+	}
+	if params.DisplayName != nil {
+		update.DisplayName = strings.TrimSpace(*params.DisplayName)
+	}
+	if params.Bio != nil {
+		update.Bio = strings.TrimSpace(*params.Bio)
+	}
+	err = validateProfileFields(update.Handle.String, update.DisplayName, update.Bio)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbUser, err = cfg.dbQueries.UpdateUserProfile(r.Context(), update)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			respondWithText(w, 409, "Handle is already taken")
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	cfg.respondWithProfile(w, r.Context(), dbUser)
+}
+
+func (cfg *apiConfig) uploadAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarBytes+(1<<20))
+	file, _, err := r.FormFile("avatar")
+	if err != nil {
+		respondWithText(w, 400, "Request must be multipart/form-data with an avatar file no larger than 5MB")
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarBytes+1))
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if len(data) > maxAvatarBytes {
+		respondWithText(w, 413, "Avatar must be no larger than 5MB")
+		return
+	}
+	img, _, err := imaging.Decode(data, maxAvatarPixels)
+	if err != nil {
+		if err == imaging.ErrUnsupportedFormat {
+			respondWithText(w, 415, "Avatar must be a PNG, JPEG or GIF image")
+			return
+		}
+		respondWithText(w, 400, "Invalid image: "+err.Error())
+		return
+	}
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	// every upload gets a fresh prefix so cached URLs of the old avatar stay valid until it is deleted
+	prefix := fmt.Sprintf("avatars/%s/%s", userID, uuid.New())
+	for name, size := range avatarSizes {
+		encoded, err := imaging.EncodeJPEG(imaging.Square(img, size))
+		if err != nil {
+			fmt.Printf("Error encoding avatar: %v\n", err)
+			w.WriteHeader(500)
+			return
+		}
+		err = cfg.blobStore.Put(r.Context(), prefix+"/"+name+".jpg", bytes.NewReader(encoded), "image/jpeg")
+		if err != nil {
+			fmt.Printf("Error storing avatar: %v\n", err)
+			w.WriteHeader(500)
+			return
+		}
+	}
+	updated, err := cfg.dbQueries.UpdateUserAvatar(r.Context(), database.UpdateUserAvatarParams{ID: userID, AvatarKey: sql.NullString{String: prefix, Valid: true}})
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if dbUser.AvatarKey.Valid {
+		for name := range avatarSizes {
+			err = cfg.blobStore.Delete(r.Context(), dbUser.AvatarKey.String+"/"+name+".jpg")
+			if err != nil {
+				fmt.Printf("Error deleting old avatar: %v\n", err)
+			}
+		}
+	}
+	cfg.respondWithProfile(w, r.Context(), updated)
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/scheduler"
+	"github.com/leiper-mike/chirpy/internal/spam"
+)
+
+const (
+	maxScheduledChirps = 100
+	maxScheduleAhead   = 365 * 24 * time.Hour
+	schedulerInterval  = 15 * time.Second
+	// a chirp that fails to publish is retried after this, doubling each time
+	schedulerRetryDelay    = time.Minute
+	schedulerMaxRetryDelay = time.Hour
+)
+
+// validatePublishAt checks a requested publish time. Times may be given with
+// any UTC offset; they are stored in UTC because the column has no time zone.
+func validatePublishAt(publishAt time.Time) error {
+	now := time.Now()
+	if !publishAt.After(now) {
+		return &requestError{400, "publish_at must be in the future"}
+	}
+	if publishAt.After(now.Add(maxScheduleAhead)) {
+		return &requestError{400, "publish_at must be within a year"}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) scheduledChirpsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	dbChirps, err := cfg.dbQueries.GetScheduledChirps(r.Context(), userID)
+	if err != nil {
+		fmt.Printf("Error getting scheduled chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	chirps, err := cfg.renderChirps(r.Context(), userID, dbChirps)
+	if err != nil {
+		fmt.Printf("Error rendering chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, chirps)
+}
+
+func (cfg *apiConfig) updateScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	type parameters struct {
+		Body      *string    `json:"body"`
+		PublishAt *time.Time `json:"publish_at"`
+		// the solved challenge, when a previous attempt asked for one
+		Verification *verificationInput `json:"verification"`
+	}
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	update := database.UpdateScheduledChirpParams{ID: chirpID, UserID: userID}
+	if params.Body != nil {
+		err = validateChirpBody(*params.Body)
+		if err != nil {
+			respondWithText(w, 400, err.Error())
+			return
+		}
+		update.Body = sql.NullString{String: *params.Body, Valid: true}
+	}
+	if params.PublishAt != nil {
+		err = validatePublishAt(*params.PublishAt)
+		if err != nil {
+			respondWithText(w, 400, err.Error())
+			return
+		}
+		update.PublishAt = sql.NullTime{Time: params.PublishAt.UTC(), Valid: true}
+	}
+	var dbChirp database.Chirp
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		// locked so the scheduler can't publish it between the checks and
+		// the update
+		current, err := q.LockChirp(r.Context(), chirpID)
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return &requestError{404, "Scheduled chirp not found"}
+			}
+			return err
+		}
+		if current.UserID != userID || !current.PublishAt.Valid || current.DeletedAt.Valid {
+			return &requestError{404, "Scheduled chirp not found"}
+		}
+		if params.Body != nil && current.RechirpOfID.Valid && *params.Body != "" {
+			return &requestError{400, "A rechirp cannot have a body"}
+		}
+		if params.Body != nil && current.QuoteOfID.Valid && strings.TrimSpace(*params.Body) == "" {
+			return &requestError{400, "A quote chirp must have a body"}
+		}
+		if current.HiddenAt.Valid {
+			return &requestError{403, "This chirp is under review and can't be edited"}
+		}
+		if params.Body == nil || *params.Body == current.Body {
+			dbChirp, err = q.UpdateScheduledChirp(r.Context(), update)
+			return err
+		}
+		verdict, err := cfg.screenChirp(r.Context(), userID, chirpID, *params.Body, params.Verification)
+		if err != nil {
+			return err
+		}
+		dbChirp, err = q.UpdateScheduledChirp(r.Context(), update)
+		if err != nil {
+			return err
+		}
+		err = recordSpamCheck(r.Context(), q, userID, uuid.NullUUID{UUID: chirpID, Valid: true}, dbChirp.Body, verdict, false)
+		if err != nil {
+			return err
+		}
+		// held like a new chirp, which the scheduler then skips
+		if verdict.Action == spam.Hold {
+			_, err = q.HideChirp(r.Context(), chirpID)
+			if err != nil {
+				return err
+			}
+			dbChirp.HiddenAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+		}
+		return nil
+	})
+	if err != nil {
+		var verifyErr *verificationError
+		if errors.As(err, &verifyErr) {
+			respondWithChallenge(w, verifyErr)
+			return
+		}
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	chirp, err := cfg.renderChirp(r.Context(), userID, dbChirp)
+	if err != nil {
+		fmt.Printf("Error rendering chirp:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, chirp)
+}
+
+func (cfg *apiConfig) cancelScheduledChirpHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	cfg.cancelScheduledChirp(w, r, userID, chirpID)
+}
+
+// cancelScheduledChirp deletes a scheduled chirp outright. Its media is
+// detached first so the uploads are cleaned up rather than leaked in storage.
+func (cfg *apiConfig) cancelScheduledChirp(w http.ResponseWriter, r *http.Request, userID, chirpID uuid.UUID) {
+	err := cfg.inTx(r.Context(), func(q *database.Queries) error {
+		err := q.DetachMedia(r.Context(), chirpID)
+		if err != nil {
+			return err
+		}
+		n, err := q.CancelScheduledChirp(r.Context(), database.CancelScheduledChirpParams{ID: chirpID, UserID: userID})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return &requestError{404, "Scheduled chirp not found"}
+		}
+		return nil
+	})
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			w.WriteHeader(reqErr.status)
+			return
+		}
+		fmt.Printf("Error cancelling chirp:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	w.WriteHeader(204)
+}
+
+// runScheduler publishes scheduled chirps once they are due. Each chirp is
+// claimed and published in its own transaction, so instances running this
+// concurrently never publish the same chirp twice.
+func (cfg *apiConfig) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		err := scheduler.Drain(ctx, scheduledChirps{cfg}, func(chirpID uuid.UUID, err error) {
+			fmt.Printf("Error publishing scheduled chirp %s: %v\n", chirpID, err)
+		})
+		if err != nil {
+			fmt.Printf("Error publishing scheduled chirps: %v\n", err)
+		}
+	}
+}
+
+// scheduledChirps is the scheduler's queue of chirps waiting to be published.
+type scheduledChirps struct {
+	cfg *apiConfig
+}
+
+func (s scheduledChirps) RunNext(ctx context.Context) (uuid.UUID, bool, error) {
+	chirpID, claimed, hasLinks := uuid.Nil, false, false
+	err := s.cfg.inTx(ctx, func(q *database.Queries) error {
+		dbChirp, err := q.ClaimDueChirp(ctx)
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return nil
+			}
+			return err
+		}
+		chirpID, claimed = dbChirp.ID, true
+		hasLinks, err = s.cfg.publishChirp(ctx, q, dbChirp)
+		return err
+	})
+	if err == nil && hasLinks {
+		s.cfg.wakeUnfurler()
+	}
+	return chirpID, claimed, err
+}
+
+// Postpone runs outside the transaction that failed, since that was rolled back.
+func (s scheduledChirps) Postpone(ctx context.Context, chirpID uuid.UUID) error {
+	return s.cfg.dbQueries.PostponeScheduledChirp(ctx, database.PostponeScheduledChirpParams{
+		RetrySeconds:    int32(schedulerRetryDelay.Seconds()),
+		MaxRetrySeconds: int32(schedulerMaxRetryDelay.Seconds()),
+		ID:              chirpID,
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/search"
+)
+
+// How far forward in time the most relevant match is moved when ranking, so
+// it outranks less relevant matches posted up to this much later.
+const searchRelevanceBoost = 30 * 24 * time.Hour
+
+func (cfg *apiConfig) searchHandler(w http.ResponseWriter, r *http.Request) {
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	query, err := search.Parse(r.URL.Query().Get("q"))
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	viewerID := cfg.viewerID(r)
+	params := database.SearchChirpsParams{
+		Query:        query.Text,
+		BoostSeconds: int32(searchRelevanceBoost.Seconds()),
+		ViewerID:     viewerID,
+		Since:        sql.NullTime{Time: query.Since, Valid: !query.Since.IsZero()},
+		Until:        sql.NullTime{Time: query.Until, Valid: !query.Until.IsZero()},
+		Tags:         query.Tags,
+		BeforeTime:   cursor.Time,
+		BeforeID:     cursor.ID,
+		RowLimit:     limit,
+	}
+	if query.From != "" {
+		author, err := cfg.dbQueries.GetUserByHandle(r.Context(), query.From)
+		if errors.Is(err, sql.ErrNoRows) {
+			respondWithJSON(w, 200, ChirpPage{Chirps: []Chirp{}})
+			return
+		}
+		if err != nil {
+			fmt.Println(err.Error())
+			w.WriteHeader(500)
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: author.ID, Valid: true}
+	}
+	rows, err := cfg.dbQueries.SearchChirps(r.Context(), params)
+	if err != nil {
+		fmt.Printf("Error searching chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	page, err := cfg.renderSearchResults(r, viewerID, rows)
+	if err != nil {
+		fmt.Printf("Error rendering chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		page.NextCursor = nextCursor(len(rows), limit, pageCursor{Time: last.RankedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, page)
+}
+
+// renderSearchResults renders matches in rank order with their highlights,
+// leaving out those the viewer's muted words hide.
+func (cfg *apiConfig) renderSearchResults(r *http.Request, viewerID uuid.UUID, rows []database.SearchChirpsRow) (ChirpPage, error) {
+	page := ChirpPage{Chirps: []Chirp{}}
+	if len(rows) == 0 {
+		return page, nil
+	}
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+	dbChirps, err := cfg.getChirpsInOrder(r.Context(), viewerID, ids)
+	if err != nil {
+		return page, err
+	}
+	chirps, err := cfg.renderChirps(r.Context(), viewerID, dbChirps)
+	if err != nil {
+		return page, err
+	}
+	headlines := make(map[uuid.UUID]string, len(rows))
+	for _, row := range rows {
+		headlines[row.ID] = row.Headline
+	}
+	for i := range chirps {
+		chirps[i].Highlights = search.Highlights(chirps[i].Body, headlines[chirps[i].ID])
+	}
+	mutedWords, err := cfg.mutedWordFilter(r.Context(), viewerID)
+	if err != nil {
+		return page, err
+	}
+	page.Chirps = filterChirps(mutedWords, viewerID, chirps)
+	return page, nil
+}
@@ -0,0 +1,360 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/entities"
+	"github.com/leiper-mike/chirpy/internal/spam"
+)
+
+const (
+	challengeLifetime = 10 * time.Minute
+
+	spamPending  = "pending"
+	spamApproved = "approved"
+	spamRejected = "rejected"
+)
+
+// loadSpamRules reads the spam pipeline's rules from the JSON file at path,
+// or uses the defaults when there isn't one.
+func loadSpamRules(path string) (spam.Rules, error) {
+	if path == "" {
+		return spam.DefaultRules(), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return spam.Rules{}, err
+	}
+	defer f.Close()
+	rules, err := spam.LoadRules(f)
+	if err != nil {
+		return spam.Rules{}, fmt.Errorf("invalid SPAM_RULES %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+type verificationInput struct {
+	Challenge string `json:"challenge"`
+	Solution  string `json:"solution"`
+}
+
+// verificationError asks the client to solve a challenge and post again.
+type verificationError struct {
+	challenge  string
+	difficulty int
+}
+
+func (e *verificationError) Error() string {
+	return "Solve the verification challenge to post this chirp"
+}
+
+type SpamCheck struct {
+	ID         uuid.UUID     `json:"id"`
+	User       *UserSummary  `json:"user,omitempty"`
+	Chirp      *Chirp        `json:"chirp,omitempty"`
+	Body       string        `json:"body"`
+	Score      float64       `json:"score"`
+	Action     string        `json:"action"`
+	Signals    []spam.Signal `json:"signals"`
+	Review     string        `json:"review,omitempty"`
+	ReviewedBy *UserSummary  `json:"reviewed_by,omitempty"`
+	CreatedAt  time.Time     `json:"created_at"`
+	ReviewedAt *time.Time    `json:"reviewed_at,omitempty"`
+}
+
+type SpamCheckPage struct {
+	Checks     []SpamCheck `json:"checks"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// screenChirp runs the spam pipeline over the body of a chirp about to be
+// posted, or about to replace the body of chirpID when it isn't uuid.Nil.
+// Rejected bodies are recorded and turned away; ones needing verification
+// are turned away with a challenge unless v solves one. Anything else gets
+// a verdict for the caller to act on.
+func (cfg *apiConfig) screenChirp(ctx context.Context, userID, chirpID uuid.UUID, body string, v *verificationInput) (spam.Verdict, error) {
+	rules := cfg.spam.Rules()
+	dbUser, err := cfg.dbQueries.GetUserByID(ctx, userID)
+	if err != nil {
+		return spam.Verdict{}, err
+	}
+	recentCount, err := cfg.dbQueries.CountChirpsSince(ctx, database.CountChirpsSinceParams{UserID: userID, ExcludeID: chirpID, CreatedAt: sql.NullTime{Time: time.Now().UTC().Add(-rules.Velocity.Window.Duration), Valid: true}})
+	if err != nil {
+		return spam.Verdict{}, err
+	}
+	recent := []string{}
+	if rules.Duplicate.Lookback > 0 {
+		recent, err = cfg.dbQueries.GetRecentChirpBodies(ctx, database.GetRecentChirpBodiesParams{UserID: userID, ExcludeID: chirpID, RowLimit: int32(rules.Duplicate.Lookback)})
+		if err != nil {
+			return spam.Verdict{}, err
+		}
+	}
+	verdict := cfg.spam.Evaluate(spam.Input{
+		Body:        body,
+		Links:       len(entities.URLs(body)),
+		AccountAge:  time.Since(dbUser.CreatedAt.Time),
+		RecentCount: int(recentCount),
+		Recent:      recent,
+	})
+	switch verdict.Action {
+	case spam.Reject:
+		// recorded outside the caller's transaction, which the error rolls
+		// back, and without the chirp, which an edit holds locked
+		err = recordSpamCheck(ctx, cfg.dbQueries, userID, uuid.NullUUID{}, body, verdict, false)
+		if err != nil {
+			return spam.Verdict{}, err
+		}
+		return spam.Verdict{}, &requestError{400, "This chirp looks like spam and was not posted"}
+	case spam.Verify:
+		subject := userID.String() + "\n" + body
+		if v == nil || spam.VerifyChallenge([]byte(cfg.secret), subject, v.Challenge, v.Solution, time.Now()) != nil {
+			challenge := spam.NewChallenge([]byte(cfg.secret), subject, rules.Difficulty, time.Now().Add(challengeLifetime))
+			return spam.Verdict{}, &verificationError{challenge: challenge, difficulty: rules.Difficulty}
+		}
+	}
+	return verdict, nil
+}
+
+// recordSpamCheck stores a verdict for moderators. Clean chirps aren't
+// recorded, and held ones join the review queue. published is set for edits
+// to chirps that were already published.
+func recordSpamCheck(ctx context.Context, q *database.Queries, userID uuid.UUID, chirpID uuid.NullUUID, body string, verdict spam.Verdict, published bool) error {
+	if verdict.Score <= 0 {
+		return nil
+	}
+	signals, err := json.Marshal(verdict.Signals)
+	if err != nil {
+		return err
+	}
+	review := sql.NullString{}
+	if verdict.Action == spam.Hold {
+		review = sql.NullString{String: spamPending, Valid: true}
+	}
+	return q.CreateSpamCheck(ctx, database.CreateSpamCheckParams{UserID: userID, ChirpID: chirpID, Body: body, Score: verdict.Score, Action: verdict.Action, Signals: signals, Review: review, Published: published})
+}
+
+func respondWithChallenge(w http.ResponseWriter, e *verificationError) {
+	type challenge struct {
+		Error      string `json:"error"`
+		Challenge  string `json:"challenge"`
+		Difficulty int    `json:"difficulty"`
+	}
+	respondWithJSON(w, 428, challenge{Error: e.Error(), Challenge: e.challenge, Difficulty: e.difficulty})
+}
+
+// spamChecksHandler lists the pipeline's verdicts for moderators. action
+// narrows it to one verdict and pending=true to held chirps awaiting review.
+func (cfg *apiConfig) spamChecksHandler(w http.ResponseWriter, r *http.Request) {
+	_, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	params := database.GetSpamChecksParams{Pending: r.URL.Query().Get("pending") == "true", BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit}
+	switch action := r.URL.Query().Get("action"); action {
+	case "":
+	case spam.Allow, spam.Verify, spam.Hold, spam.Reject:
+		params.Action = sql.NullString{String: action, Valid: true}
+	default:
+		respondWithText(w, 400, "action must be allow, verify, hold or reject")
+		return
+	}
+	dbChecks, err := cfg.dbQueries.GetSpamChecks(r.Context(), params)
+	if err != nil {
+		fmt.Printf("Error getting spam checks:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	checks, err := cfg.renderSpamChecks(r.Context(), dbChecks)
+	if err != nil {
+		fmt.Printf("Error rendering spam checks:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	page := SpamCheckPage{Checks: checks}
+	if len(dbChecks) > 0 {
+		last := dbChecks[len(dbChecks)-1]
+		page.NextCursor = nextCursor(len(dbChecks), limit, pageCursor{Time: last.CreatedAt, ID: last.ID})
+	}
+	respondWithJSON(w, 200, page)
+}
+
+func (cfg *apiConfig) renderSpamChecks(ctx context.Context, dbChecks []database.SpamCheck) ([]SpamCheck, error) {
+	userIDs := []uuid.UUID{}
+	chirpIDs := []uuid.UUID{}
+	for _, dbCheck := range dbChecks {
+		userIDs = append(userIDs, dbCheck.UserID)
+		if dbCheck.ReviewedBy.Valid {
+			userIDs = append(userIDs, dbCheck.ReviewedBy.UUID)
+		}
+		if dbCheck.ChirpID.Valid {
+			chirpIDs = append(chirpIDs, dbCheck.ChirpID.UUID)
+		}
+	}
+	summaries, err := cfg.getUserSummaries(ctx, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	chirps := map[uuid.UUID]*Chirp{}
+	if len(chirpIDs) > 0 {
+		dbChirps, err := cfg.dbQueries.GetChirpsByIDs(ctx, chirpIDs)
+		if err != nil {
+			return nil, err
+		}
+		rendered, err := cfg.renderChirps(ctx, uuid.Nil, dbChirps)
+		if err != nil {
+			return nil, err
+		}
+		for i := range rendered {
+			chirps[rendered[i].ID] = &rendered[i]
+		}
+	}
+	checks := make([]SpamCheck, 0, len(dbChecks))
+	for _, dbCheck := range dbChecks {
+		check := SpamCheck{
+			ID:        dbCheck.ID,
+			User:      summaries[dbCheck.UserID],
+			Body:      dbCheck.Body,
+			Score:     dbCheck.Score,
+			Action:    dbCheck.Action,
+			Review:    dbCheck.Review.String,
+			CreatedAt: dbCheck.CreatedAt,
+		}
+		err = json.Unmarshal(dbCheck.Signals, &check.Signals)
+		if err != nil {
+			return nil, err
+		}
+		if dbCheck.ChirpID.Valid {
+			check.Chirp = chirps[dbCheck.ChirpID.UUID]
+		}
+		if dbCheck.ReviewedBy.Valid {
+			check.ReviewedBy = summaries[dbCheck.ReviewedBy.UUID]
+		}
+		if dbCheck.ReviewedAt.Valid {
+			check.ReviewedAt = &dbCheck.ReviewedAt.Time
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// reviewSpamHandler settles a held chirp: approving it publishes it as if it
+// had just been posted, or as if it had just been edited when the edit was
+// held, and rejecting it deletes it.
+func (cfg *apiConfig) reviewSpamHandler(w http.ResponseWriter, r *http.Request) {
+	moderatorID, ok := cfg.authenticateModerator(w, r)
+	if !ok {
+		return
+	}
+	checkID, err := uuid.Parse(r.PathValue("checkID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	type parameters struct {
+		Approve bool   `json:"approve"`
+		Note    string `json:"note"`
+	}
+	params := parameters{}
+	err = json.NewDecoder(r.Body).Decode(&params)
+	if err != nil {
+		respondWithText(w, 400, "Invalid request body")
+		return
+	}
+	review, action := spamRejected, "reject_chirp"
+	if params.Approve {
+		review, action = spamApproved, "approve_chirp"
+	}
+	var dbCheck database.SpamCheck
+	hasLinks := false
+	err = cfg.inTx(r.Context(), func(q *database.Queries) error {
+		dbCheck, err = q.GetSpamCheck(r.Context(), checkID)
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return &requestError{404, "Spam check not found"}
+			}
+			return err
+		}
+		if dbCheck.Review.String != spamPending {
+			return &requestError{409, "Only held chirps waiting for review can be reviewed"}
+		}
+		dbChirp, err := q.GetChirp(r.Context(), dbCheck.ChirpID.UUID)
+		if err != nil {
+			return err
+		}
+		// the author may have deleted it while it waited
+		if !dbChirp.DeletedAt.Valid {
+			if params.Approve {
+				err = q.UnhideChirp(r.Context(), dbChirp.ID)
+				if err != nil {
+					return err
+				}
+				dbChirp.HiddenAt = sql.NullTime{}
+				switch {
+				case dbCheck.Published:
+					// a held edit is only indexed once it is approved
+					hasLinks, err = cfg.reindexChirp(r.Context(), q, dbChirp)
+					if err == nil {
+						err = publishChirpEvent(r.Context(), q, streamEventEdited, dbChirp)
+					}
+				case !dbChirp.PublishAt.Valid:
+					hasLinks, err = cfg.publishChirp(r.Context(), q, dbChirp)
+				}
+				// scheduled chirps are left for the scheduler to publish
+			} else {
+				err = removeChirp(r.Context(), q, dbChirp)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		dbCheck, err = q.ReviewSpamCheck(r.Context(), database.ReviewSpamCheckParams{ID: checkID, Review: sql.NullString{String: review, Valid: true}, ReviewedBy: uuid.NullUUID{UUID: moderatorID, Valid: true}})
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return &requestError{409, "Only held chirps waiting for review can be reviewed"}
+			}
+			return err
+		}
+		return q.CreateModerationAction(r.Context(), database.CreateModerationActionParams{
+			ModeratorID:   uuid.NullUUID{UUID: moderatorID, Valid: true},
+			Action:        action,
+			TargetUserID:  uuid.NullUUID{UUID: dbCheck.UserID, Valid: true},
+			TargetChirpID: dbCheck.ChirpID,
+			Note:          strings.TrimSpace(params.Note),
+		})
+	})
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Printf("Error reviewing spam check:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if hasLinks {
+		cfg.wakeUnfurler()
+	}
+	checks, err := cfg.renderSpamChecks(r.Context(), []database.SpamCheck{dbCheck})
+	if err != nil {
+		fmt.Printf("Error rendering spam check:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	respondWithJSON(w, 200, checks[0])
+}
@@ -0,0 +1,55 @@
+-- name: BlockUser :execrows
+INSERT INTO blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+-- name: UnblockUser :execrows
+DELETE FROM blocks
+WHERE blocker_id = $1 AND blocked_id = $2;
+-- name: MuteUser :execrows
+INSERT INTO mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+-- name: UnmuteUser :execrows
+DELETE FROM mutes
+WHERE muter_id = $1 AND muted_id = $2;
+-- name: GetBlockedUsers :many
+SELECT users.id, users.handle, users.display_name, users.avatar_key, blocks.created_at AS blocked_at
+FROM blocks
+JOIN users ON users.id = blocks.blocked_id
+WHERE blocks.blocker_id = sqlc.arg(user_id)
+AND (blocks.created_at, blocks.blocked_id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY blocks.created_at DESC, blocks.blocked_id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetMutedUsers :many
+SELECT users.id, users.handle, users.display_name, users.avatar_key, mutes.created_at AS muted_at
+FROM mutes
+JOIN users ON users.id = mutes.muted_id
+WHERE mutes.muter_id = sqlc.arg(user_id)
+AND (mutes.created_at, mutes.muted_id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY mutes.created_at DESC, mutes.muted_id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetBlockedUserIDs :many
+-- everyone on either side of a block with the user
+SELECT blocks.blocked_id AS user_id FROM blocks WHERE blocks.blocker_id = $1
+UNION
+SELECT blocks.blocker_id AS user_id FROM blocks WHERE blocks.blocked_id = $1;
+-- name: GetMutedUserIDs :many
+SELECT muted_id FROM mutes
+WHERE mutes.muter_id = $1;
+-- name: BlockExists :one
+-- whether the user and any of the others have blocked one another
+SELECT EXISTS (
+    SELECT 1 FROM unnest(sqlc.arg(user_ids)::uuid[]) AS other(id)
+    WHERE is_blocked(sqlc.arg(user_id)::uuid, other.id)
+)::boolean AS blocked;
+-- name: BlockedByMember :one
+-- whether anyone in the conversation has blocked the user; the other side of
+-- a direct conversation counts even after leaving since a message brings them back
+SELECT EXISTS (
+    SELECT 1 FROM conversation_members
+    JOIN conversations ON conversations.id = conversation_members.conversation_id
+    JOIN blocks ON blocks.blocker_id = conversation_members.user_id
+    AND blocks.blocked_id = sqlc.arg(user_id)
+    WHERE conversation_members.conversation_id = sqlc.arg(conversation_id)
+    AND (conversation_members.left_at IS NULL OR conversations.direct_key IS NOT NULL)
+)::boolean AS blocked;
@@ -0,0 +1,32 @@
+-- name: LockChirp :one
+SELECT * FROM chirps WHERE chirps.id = $1 FOR UPDATE;
+-- name: EditChirp :one
+UPDATE chirps
+SET body = $2, updated_at = NOW(), edited_at = NOW()
+WHERE chirps.id = $1
+RETURNING *;
+-- name: CreateChirpRevision :exec
+INSERT INTO chirp_revisions (id, chirp_id, body, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3);
+-- name: CountChirpRevisions :one
+SELECT COUNT(*) FROM chirp_revisions WHERE chirp_revisions.chirp_id = $1;
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions
+WHERE chirp_revisions.chirp_id = $1
+ORDER BY chirp_revisions.created_at DESC;
+-- name: DeleteChirpRevisions :exec
+DELETE FROM chirp_revisions WHERE chirp_revisions.chirp_id = $1;
+-- name: UntagChirp :exec
+-- removes the tags an edited chirp no longer uses; the rest keep their
+-- original created_at so edits don't count towards trending again
+DELETE FROM chirp_hashtags
+USING hashtags
+WHERE chirp_hashtags.chirp_id = sqlc.arg(chirp_id)::uuid
+AND hashtags.id = chirp_hashtags.hashtag_id
+AND NOT (hashtags.tag = ANY(sqlc.arg(tags)::text[]));
+-- name: RemoveChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_mentions.chirp_id = sqlc.arg(chirp_id)::uuid
+AND NOT (chirp_mentions.handle = ANY(sqlc.arg(handles)::text[]));
+-- name: ClearChirpLinks :exec
+DELETE FROM chirp_links WHERE chirp_links.chirp_id = $1;
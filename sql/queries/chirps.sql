@@ -1,16 +1,47 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to_id, conversation_id, rechirp_of_id, quote_of_id, publish_at, visibility)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
 )
 RETURNING *;
 -- name: DeleteChirp :exec
 DELETE FROM chirps WHERE chirps.id = $1;
+-- name: TombstoneChirp :one
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE chirps.id = $1 AND chirps.deleted_at IS NULL
+RETURNING *;
 -- name: GetAllChirps :many
-SELECT * FROM chirps;
+SELECT * FROM chirps
+WHERE chirps.deleted_at IS NULL AND chirps.publish_at IS NULL
+AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid);
 -- name: GetChirp :one
-SELECT * FROM chirps WHERE chirps.id = $1;
\ No newline at end of file
+SELECT * FROM chirps WHERE chirps.id = $1;
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps WHERE chirps.id = ANY(sqlc.arg(ids)::uuid[]);
+-- name: GetVisibleChirpsByIDs :many
+SELECT * FROM chirps
+WHERE chirps.id = ANY(sqlc.arg(ids)::uuid[])
+AND chirps.publish_at IS NULL
+AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid);
+-- name: CanViewChirp :one
+SELECT can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid) AS visible
+FROM chirps WHERE chirps.id = sqlc.arg(chirp_id);
+-- name: IncrementReplyCount :exec
+UPDATE chirps SET reply_count = reply_count + 1 WHERE chirps.id = $1;
+-- name: DecrementReplyCount :exec
+UPDATE chirps SET reply_count = GREATEST(reply_count - 1, 0) WHERE chirps.id = $1;
+-- name: IncrementRechirpCount :exec
+UPDATE chirps SET rechirp_count = rechirp_count + 1 WHERE chirps.id = $1;
+-- name: DecrementRechirpCount :exec
+UPDATE chirps SET rechirp_count = GREATEST(rechirp_count - 1, 0) WHERE chirps.id = $1;
\ No newline at end of file
@@ -0,0 +1,33 @@
+-- name: CreateDraft :one
+INSERT INTO drafts (id, user_id, body, in_reply_to_id, quote_of_id, created_at, updated_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+-- name: CountDrafts :one
+SELECT COUNT(*) FROM drafts WHERE drafts.user_id = $1;
+-- name: GetDrafts :many
+SELECT * FROM drafts
+WHERE drafts.user_id = $1
+ORDER BY drafts.updated_at DESC, drafts.id;
+-- name: GetDraft :one
+SELECT * FROM drafts
+WHERE drafts.id = $1 AND drafts.user_id = $2;
+-- name: UpdateDraft :one
+-- When last_updated_at is given the save only applies if nobody else has
+-- saved the draft since, so two devices can't silently overwrite each other.
+UPDATE drafts
+SET body = sqlc.arg(body), in_reply_to_id = sqlc.narg(in_reply_to_id), quote_of_id = sqlc.narg(quote_of_id), updated_at = NOW()
+WHERE drafts.id = sqlc.arg(id) AND drafts.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(last_updated_at)::timestamp IS NULL OR drafts.updated_at = sqlc.narg(last_updated_at)::timestamp)
+RETURNING *;
+-- name: DeleteDraft :execrows
+DELETE FROM drafts
+WHERE drafts.id = sqlc.arg(id) AND drafts.user_id = sqlc.arg(user_id)
+AND (sqlc.narg(last_updated_at)::timestamp IS NULL OR drafts.updated_at = sqlc.narg(last_updated_at)::timestamp);
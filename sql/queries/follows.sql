@@ -0,0 +1,31 @@
+-- name: FollowUser :execrows
+INSERT INTO follows (follower_id, followee_id, created_at)
+SELECT sqlc.arg(follower_id)::uuid, sqlc.arg(followee_id)::uuid, NOW()
+WHERE NOT is_blocked(sqlc.arg(follower_id)::uuid, sqlc.arg(followee_id)::uuid)
+ON CONFLICT DO NOTHING;
+-- name: UnfollowUser :execrows
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+-- name: GetFollowers :many
+SELECT users.id, users.handle, users.display_name, users.avatar_key, follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.follower_id
+WHERE follows.followee_id = sqlc.arg(user_id)
+AND (follows.created_at, follows.follower_id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY follows.created_at DESC, follows.follower_id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetFollowing :many
+SELECT users.id, users.handle, users.display_name, users.avatar_key, follows.created_at AS followed_at
+FROM follows
+JOIN users ON users.id = follows.followee_id
+WHERE follows.follower_id = sqlc.arg(user_id)
+AND (follows.created_at, follows.followee_id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY follows.created_at DESC, follows.followee_id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetFollowCounts :one
+SELECT
+    (SELECT COUNT(*) FROM follows WHERE follows.followee_id = $1) AS follower_count,
+    (SELECT COUNT(*) FROM follows WHERE follows.follower_id = $1) AS following_count;
+-- name: GetFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follows.follower_id = $1;
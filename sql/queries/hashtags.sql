@@ -0,0 +1,47 @@
+-- name: TagChirp :exec
+WITH tags AS (
+    INSERT INTO hashtags (id, tag, created_at)
+    SELECT gen_random_uuid(), unnest(sqlc.arg(tags)::text[]), NOW()
+    ON CONFLICT (tag) DO UPDATE SET tag = EXCLUDED.tag
+    RETURNING id
+)
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id, created_at)
+SELECT sqlc.arg(chirp_id)::uuid, tags.id, NOW()
+FROM tags
+ON CONFLICT DO NOTHING;
+-- name: GetHashtagChirps :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE hashtags.tag = sqlc.arg(tag)::text
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid)
+AND (chirps.created_at, chirps.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetHashtagUsage :many
+-- Per tag usage in the last window_seconds and in the window of the same
+-- length before that, the inputs to trending scores.
+SELECT hashtags.tag,
+    (COUNT(*) FILTER (WHERE chirp_hashtags.created_at >= NOW() - sqlc.arg(window_seconds)::int * INTERVAL '1 second'))::int AS current_count,
+    (COUNT(*) FILTER (WHERE chirp_hashtags.created_at < NOW() - sqlc.arg(window_seconds)::int * INTERVAL '1 second'))::int AS previous_count
+FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.created_at >= NOW() - 2 * sqlc.arg(window_seconds)::int * INTERVAL '1 second'
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND chirps.hidden_at IS NULL
+AND chirps.visibility = 'public'
+AND account_active(chirps.user_id)
+GROUP BY hashtags.tag;
+-- name: ClearTrendingHashtags :exec
+DELETE FROM trending_hashtags WHERE window_name = $1;
+-- name: InsertTrendingHashtag :exec
+INSERT INTO trending_hashtags (window_name, rank, tag, chirp_count, score, computed_at)
+VALUES ($1, $2, $3, $4, $5, NOW());
+-- name: GetTrendingHashtags :many
+SELECT * FROM trending_hashtags
+WHERE window_name = $1
+ORDER BY rank;
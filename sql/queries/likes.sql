@@ -0,0 +1,25 @@
+-- name: LikeChirp :execrows
+INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+-- name: UnlikeChirp :execrows
+DELETE FROM chirp_likes
+WHERE user_id = $1 AND chirp_id = $2;
+-- name: IncrementLikeCount :exec
+UPDATE chirps SET like_count = like_count + 1 WHERE chirps.id = $1;
+-- name: DecrementLikeCount :exec
+UPDATE chirps SET like_count = GREATEST(like_count - 1, 0) WHERE chirps.id = $1;
+-- name: GetLikedChirpIDs :many
+SELECT chirp_id FROM chirp_likes
+WHERE chirp_likes.user_id = sqlc.arg(user_id) AND chirp_likes.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+-- name: GetUserLikes :many
+SELECT chirp_likes.chirp_id, chirp_likes.created_at AS liked_at
+FROM chirp_likes
+JOIN chirps ON chirps.id = chirp_likes.chirp_id
+WHERE chirp_likes.user_id = sqlc.arg(user_id)
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid)
+AND (chirp_likes.created_at, chirp_likes.chirp_id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY chirp_likes.created_at DESC, chirp_likes.chirp_id DESC
+LIMIT sqlc.arg(row_limit);
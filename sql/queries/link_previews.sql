@@ -0,0 +1,40 @@
+-- name: RequestLinkPreviews :exec
+-- queues urls that have never been fetched, or whose cached preview is stale
+INSERT INTO link_previews (url, status, next_attempt_at, created_at)
+SELECT unnest(sqlc.arg(urls)::text[]), 'pending', NOW(), NOW()
+ON CONFLICT (url) DO UPDATE SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+WHERE link_previews.status <> 'pending'
+AND link_previews.fetched_at < NOW() - sqlc.arg(max_age_seconds)::integer * INTERVAL '1 second';
+-- name: AddChirpLinks :exec
+INSERT INTO chirp_links (chirp_id, url, position)
+SELECT sqlc.arg(chirp_id)::uuid, links.url, links.position - 1
+FROM unnest(sqlc.arg(urls)::text[]) WITH ORDINALITY AS links(url, position)
+ON CONFLICT DO NOTHING;
+-- name: ClaimLinkPreviews :many
+UPDATE link_previews
+SET attempts = attempts + 1, next_attempt_at = NOW() + sqlc.arg(lease_seconds)::integer * INTERVAL '1 second'
+WHERE url IN (
+    SELECT url FROM link_previews
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(row_limit)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+-- name: CompleteLinkPreview :exec
+UPDATE link_previews
+SET status = 'ok', title = $2, description = $3, image_url = $4, site_name = $5, fetched_at = NOW()
+WHERE url = $1;
+-- name: FailLinkPreview :exec
+UPDATE link_previews
+SET status = CASE WHEN attempts >= sqlc.arg(max_attempts)::integer THEN 'failed' ELSE 'pending' END,
+    next_attempt_at = NOW() + sqlc.arg(retry_seconds)::integer * INTERVAL '1 second',
+    fetched_at = NOW()
+WHERE url = sqlc.arg(url);
+-- name: GetChirpLinkPreviews :many
+SELECT chirp_links.chirp_id, link_previews.url, link_previews.title, link_previews.description, link_previews.image_url, link_previews.site_name
+FROM chirp_links
+JOIN link_previews ON link_previews.url = chirp_links.url
+WHERE chirp_links.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+AND link_previews.status = 'ok'
+ORDER BY chirp_links.chirp_id, chirp_links.position;
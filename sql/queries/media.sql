@@ -0,0 +1,49 @@
+-- name: CreateMedia :one
+INSERT INTO chirp_media (id, user_id, chirp_id, position, kind, content_type, original_key, thumbnail_key, width, height, size_bytes, alt_text, created_at)
+VALUES (
+    $1,
+    $2,
+    NULL,
+    0,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8,
+    $9,
+    $10,
+    NOW()
+)
+RETURNING *;
+-- name: GetMedia :one
+SELECT * FROM chirp_media
+WHERE chirp_media.id = $1;
+-- name: UpdateMediaAltText :one
+UPDATE chirp_media SET alt_text = $3
+WHERE chirp_media.id = $1 AND chirp_media.user_id = $2
+RETURNING *;
+-- name: AttachMedia :execrows
+-- media is shown in the order its IDs were given
+UPDATE chirp_media
+SET chirp_id = sqlc.arg(chirp_id), position = array_position(sqlc.arg(ids)::uuid[], chirp_media.id) - 1
+WHERE chirp_media.id = ANY(sqlc.arg(ids)::uuid[])
+AND chirp_media.user_id = sqlc.arg(user_id)
+AND chirp_media.chirp_id IS NULL;
+-- name: GetChirpMedia :many
+SELECT * FROM chirp_media
+WHERE chirp_media.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY chirp_media.chirp_id, chirp_media.position;
+-- name: GetUnattachedMedia :many
+SELECT * FROM chirp_media
+WHERE chirp_media.chirp_id IS NULL
+AND chirp_media.created_at < NOW() - sqlc.arg(age_seconds)::integer * INTERVAL '1 second'
+ORDER BY chirp_media.created_at
+LIMIT sqlc.arg(row_limit);
+-- name: DeleteUnattachedMedia :execrows
+DELETE FROM chirp_media
+WHERE chirp_media.id = $1 AND chirp_media.chirp_id IS NULL;
+-- name: DetachMedia :exec
+-- detached media is removed by the cleanup worker like an unused upload
+UPDATE chirp_media SET chirp_id = NULL
+WHERE chirp_media.chirp_id = $1;
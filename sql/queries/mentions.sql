@@ -0,0 +1,11 @@
+-- name: AddChirpMentions :many
+INSERT INTO chirp_mentions (chirp_id, user_id, handle)
+SELECT sqlc.arg(chirp_id)::uuid, users.id, users.handle
+FROM users
+WHERE users.handle = ANY(sqlc.arg(handles)::text[])
+AND NOT is_blocked(users.id, sqlc.arg(author_id)::uuid)
+ON CONFLICT DO NOTHING
+RETURNING user_id;
+-- name: GetChirpMentions :many
+SELECT * FROM chirp_mentions
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
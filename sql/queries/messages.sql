@@ -0,0 +1,69 @@
+-- name: GetDirectConversation :one
+SELECT * FROM conversations WHERE conversations.direct_key = $1;
+-- name: CreateConversation :one
+-- two users starting the same direct conversation at once both get the one row
+INSERT INTO conversations (id, direct_key, title, creator_id, created_at, updated_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW(), NOW())
+ON CONFLICT (direct_key) DO UPDATE SET direct_key = EXCLUDED.direct_key
+RETURNING *;
+-- name: GetConversation :one
+SELECT * FROM conversations WHERE conversations.id = $1;
+-- name: AddConversationMembers :exec
+-- members rejoining a conversation they left keep their old read position
+INSERT INTO conversation_members (conversation_id, user_id, joined_at, last_read_at)
+SELECT sqlc.arg(conversation_id)::uuid, unnest(sqlc.arg(user_ids)::uuid[]), NOW(), NOW()
+ON CONFLICT (conversation_id, user_id) DO UPDATE SET left_at = NULL;
+-- name: GetConversationMember :one
+SELECT * FROM conversation_members
+WHERE conversation_members.conversation_id = $1 AND conversation_members.user_id = $2
+AND conversation_members.left_at IS NULL;
+-- name: GetConversationMembers :many
+SELECT * FROM conversation_members
+WHERE conversation_members.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+AND conversation_members.left_at IS NULL
+ORDER BY conversation_members.conversation_id, conversation_members.joined_at;
+-- name: GetConversations :many
+SELECT conversations.* FROM conversations
+JOIN conversation_members ON conversation_members.conversation_id = conversations.id
+WHERE conversation_members.user_id = sqlc.arg(user_id)
+AND conversation_members.left_at IS NULL
+AND (conversations.updated_at, conversations.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY conversations.updated_at DESC, conversations.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: CreateMessage :one
+INSERT INTO messages (id, conversation_id, sender_id, body, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW())
+RETURNING *;
+-- name: TouchConversation :exec
+UPDATE conversations SET updated_at = NOW() WHERE conversations.id = $1;
+-- name: GetMessages :many
+SELECT * FROM messages
+WHERE messages.conversation_id = sqlc.arg(conversation_id)
+AND (messages.created_at, messages.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY messages.created_at DESC, messages.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetMessagesByIDs :many
+SELECT * FROM messages WHERE messages.id = ANY(sqlc.arg(ids)::uuid[]);
+-- name: GetLastMessages :many
+SELECT DISTINCT ON (messages.conversation_id) * FROM messages
+WHERE messages.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+ORDER BY messages.conversation_id, messages.created_at DESC, messages.id DESC;
+-- name: GetUnreadMessageCounts :many
+SELECT messages.conversation_id, COUNT(*)::int AS unread
+FROM messages
+JOIN conversation_members ON conversation_members.conversation_id = messages.conversation_id
+AND conversation_members.user_id = sqlc.arg(user_id)
+WHERE messages.conversation_id = ANY(sqlc.arg(conversation_ids)::uuid[])
+AND messages.sender_id <> sqlc.arg(user_id)
+AND messages.created_at > conversation_members.last_read_at
+GROUP BY messages.conversation_id;
+-- name: MarkConversationRead :execrows
+UPDATE conversation_members SET last_read_at = NOW()
+WHERE conversation_members.conversation_id = $1 AND conversation_members.user_id = $2
+AND conversation_members.left_at IS NULL;
+-- name: LeaveConversation :execrows
+UPDATE conversation_members SET left_at = NOW()
+WHERE conversation_members.conversation_id = $1 AND conversation_members.user_id = $2
+AND conversation_members.left_at IS NULL;
+-- name: GetUsersByHandles :many
+SELECT * FROM users WHERE users.handle = ANY(sqlc.arg(handles)::text[]);
@@ -0,0 +1,22 @@
+-- name: CreateMutedWord :one
+-- adding a rule that already exists, even an expired one, replaces it
+INSERT INTO muted_words (id, user_id, kind, pattern, action, expires_at, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, NOW())
+ON CONFLICT (user_id, kind, pattern) DO UPDATE
+SET action = EXCLUDED.action, expires_at = EXCLUDED.expires_at, created_at = EXCLUDED.created_at
+RETURNING *;
+-- name: CountMutedWords :one
+SELECT COUNT(*) FROM muted_words
+WHERE muted_words.user_id = $1
+AND (muted_words.expires_at IS NULL OR muted_words.expires_at > NOW());
+-- name: GetMutedWords :many
+SELECT * FROM muted_words
+WHERE muted_words.user_id = $1
+AND (muted_words.expires_at IS NULL OR muted_words.expires_at > NOW())
+ORDER BY muted_words.created_at DESC, muted_words.id DESC;
+-- name: DeleteMutedWord :execrows
+DELETE FROM muted_words
+WHERE muted_words.id = $1 AND muted_words.user_id = $2;
+-- name: DeleteExpiredMutedWords :exec
+DELETE FROM muted_words
+WHERE muted_words.user_id = $1 AND muted_words.expires_at <= NOW();
@@ -0,0 +1,70 @@
+-- name: UpsertNotification :one
+INSERT INTO notifications (id, user_id, type, chirp_id, group_key, created_at, updated_at, actor_count, read_at)
+VALUES (
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    $4,
+    NOW(),
+    NOW(),
+    0,
+    NULL
+)
+ON CONFLICT (user_id, group_key) WHERE read_at IS NULL
+DO UPDATE SET updated_at = NOW()
+RETURNING id;
+-- name: AddNotificationActor :execrows
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+-- name: IncrementNotificationActorCount :exec
+UPDATE notifications SET actor_count = actor_count + 1 WHERE notifications.id = $1;
+-- name: GetNotifications :many
+SELECT * FROM notifications
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND notification_visible(notifications.id, notifications.user_id, notifications.chirp_id)
+AND (notifications.updated_at, notifications.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY notifications.updated_at DESC, notifications.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetNotificationsByIDs :many
+SELECT * FROM notifications
+WHERE notifications.id = ANY(sqlc.arg(ids)::uuid[]);
+-- name: GetNotificationActors :many
+SELECT notification_id, actor_id FROM (
+    SELECT notification_id, actor_id, ROW_NUMBER() OVER (PARTITION BY notification_id ORDER BY created_at DESC) AS position
+    FROM notification_actors
+    WHERE notification_actors.notification_id = ANY(sqlc.arg(notification_ids)::uuid[])
+) ranked
+WHERE position <= sqlc.arg(per_notification)::integer
+ORDER BY notification_id, position;
+-- name: CountUnreadNotifications :one
+-- counts what GetNotifications would list
+SELECT COUNT(*) FROM notifications
+WHERE notifications.user_id = $1 AND notifications.read_at IS NULL
+AND notification_visible(notifications.id, notifications.user_id, notifications.chirp_id);
+-- name: MarkNotificationsRead :execrows
+UPDATE notifications SET read_at = NOW()
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND notifications.id = ANY(sqlc.arg(ids)::uuid[])
+AND notifications.read_at IS NULL;
+-- name: MarkAllNotificationsRead :execrows
+UPDATE notifications SET read_at = NOW()
+WHERE notifications.user_id = $1 AND notifications.read_at IS NULL;
+-- name: NotificationEnabled :one
+-- nothing reaches a user from someone they have blocked, been blocked by, or
+-- muted, or from a limited account
+SELECT (COALESCE((
+    SELECT enabled FROM notification_preferences
+    WHERE notification_preferences.user_id = sqlc.arg(user_id) AND notification_preferences.type = sqlc.arg(type)
+), true)
+AND NOT is_blocked(sqlc.arg(user_id)::uuid, sqlc.arg(actor_id)::uuid)
+AND NOT is_muted(sqlc.arg(user_id)::uuid, sqlc.arg(actor_id)::uuid)
+AND account_active(sqlc.arg(actor_id)::uuid))::boolean AS enabled;
+-- name: GetNotificationPreferences :many
+SELECT * FROM notification_preferences
+WHERE notification_preferences.user_id = $1;
+-- name: SetNotificationPreference :exec
+INSERT INTO notification_preferences (user_id, type, enabled)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, type) DO UPDATE SET enabled = EXCLUDED.enabled;
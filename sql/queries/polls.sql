@@ -0,0 +1,37 @@
+-- name: CreatePoll :exec
+INSERT INTO polls (chirp_id, duration_seconds, created_at)
+VALUES ($1, $2, NOW());
+-- name: AddPollOptions :exec
+INSERT INTO poll_options (chirp_id, position, text)
+SELECT sqlc.arg(chirp_id)::uuid, options.position - 1, options.text
+FROM unnest(sqlc.arg(options)::text[]) WITH ORDINALITY AS options(text, position);
+-- name: OpenPoll :exec
+UPDATE polls
+SET closes_at = NOW() + duration_seconds * INTERVAL '1 second'
+WHERE polls.chirp_id = $1 AND polls.closes_at IS NULL;
+-- name: GetPoll :one
+SELECT * FROM polls WHERE polls.chirp_id = $1;
+-- name: GetPolls :many
+SELECT * FROM polls WHERE polls.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+-- name: GetPollOptions :many
+SELECT * FROM poll_options
+WHERE poll_options.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[])
+ORDER BY poll_options.chirp_id, poll_options.position;
+-- name: GetPollVotes :many
+SELECT poll_votes.chirp_id, poll_votes.position FROM poll_votes
+WHERE poll_votes.user_id = sqlc.arg(user_id)
+AND poll_votes.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+-- name: CastPollVote :execrows
+INSERT INTO poll_votes (chirp_id, user_id, position, created_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT DO NOTHING;
+-- name: CountPollVote :exec
+WITH option AS (
+    UPDATE poll_options SET vote_count = vote_count + 1
+    WHERE poll_options.chirp_id = $1 AND poll_options.position = $2
+)
+UPDATE polls SET total_votes = total_votes + 1
+WHERE polls.chirp_id = $1;
+-- name: ClosePolls :execrows
+UPDATE polls SET closed_at = NOW()
+WHERE polls.closed_at IS NULL AND polls.closes_at <= NOW();
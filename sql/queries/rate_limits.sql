@@ -0,0 +1,14 @@
+-- name: LockRateLimit :one
+-- creates the bucket full if there isn't one and locks it either way until
+-- the transaction ends, so instances take turns spending from it
+INSERT INTO rate_limits (key, tokens, updated_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (key) DO UPDATE SET key = EXCLUDED.key
+RETURNING tokens, updated_at, NOW()::timestamp AS now;
+-- name: SaveRateLimit :exec
+UPDATE rate_limits SET tokens = $2, updated_at = $3
+WHERE rate_limits.key = $1;
+-- name: DeleteIdleRateLimits :exec
+-- buckets idle this long are full again, the same as having none
+DELETE FROM rate_limits
+WHERE rate_limits.updated_at < NOW() - make_interval(secs => sqlc.arg(idle_seconds)::float8);
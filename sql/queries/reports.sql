@@ -0,0 +1,67 @@
+-- name: CreateReport :one
+-- a reporter repeating an open report gets no row back
+INSERT INTO reports (id, reporter_id, user_id, chirp_id, reason, details, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, NOW())
+ON CONFLICT DO NOTHING
+RETURNING *;
+-- name: CountChirpReporters :one
+SELECT COUNT(DISTINCT reports.reporter_id) FROM reports
+WHERE reports.chirp_id = $1 AND reports.status = 'open'
+-- reports from limited accounts don't count towards hiding a chirp
+AND account_active(reports.reporter_id);
+-- name: HideChirp :execrows
+UPDATE chirps SET hidden_at = NOW()
+WHERE chirps.id = $1 AND chirps.hidden_at IS NULL;
+-- name: UnhideChirp :exec
+UPDATE chirps SET hidden_at = NULL
+WHERE chirps.id = $1;
+-- name: GetReport :one
+SELECT * FROM reports WHERE reports.id = $1;
+-- name: GetReports :many
+-- the moderator queue; assigned_to narrows it to one moderator's reports and
+-- unassigned to the ones nobody has picked up
+SELECT * FROM reports
+WHERE reports.status = sqlc.arg(status)
+AND (sqlc.narg(assigned_to)::uuid IS NULL OR reports.assigned_to = sqlc.narg(assigned_to))
+AND (NOT sqlc.arg(unassigned)::boolean OR reports.assigned_to IS NULL)
+AND (reports.created_at, reports.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY reports.created_at DESC, reports.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetReportCounts :many
+-- how many open reports each target has, so moderators can see volume
+SELECT reports.chirp_id, COUNT(*)::int AS open_reports FROM reports
+WHERE reports.chirp_id = ANY(sqlc.arg(chirp_ids)::uuid[]) AND reports.status = 'open'
+GROUP BY reports.chirp_id;
+-- name: AssignReport :one
+UPDATE reports SET assigned_to = $2
+WHERE reports.id = $1 AND reports.status = 'open'
+RETURNING *;
+-- name: ResolveReports :many
+-- resolves the report and every other open report about the same chirp, or
+-- about the same user for reports not tied to a chirp
+UPDATE reports
+SET status = 'resolved', resolution = sqlc.arg(resolution), resolved_by = sqlc.arg(resolved_by), resolved_at = NOW()
+WHERE reports.status = 'open'
+AND (reports.id = sqlc.arg(id)
+    OR (sqlc.narg(chirp_id)::uuid IS NOT NULL AND reports.chirp_id = sqlc.narg(chirp_id))
+    OR (sqlc.narg(chirp_id)::uuid IS NULL AND reports.chirp_id IS NULL AND reports.user_id = sqlc.arg(user_id)))
+RETURNING id;
+-- name: CreateModerationAction :exec
+INSERT INTO moderation_actions (id, moderator_id, report_id, action, target_user_id, target_chirp_id, note, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, NOW());
+-- name: GetModerationActions :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg(target_user_id)::uuid IS NULL OR moderation_actions.target_user_id = sqlc.narg(target_user_id))
+AND (moderation_actions.created_at, moderation_actions.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY moderation_actions.created_at DESC, moderation_actions.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: SetAccountState :one
+UPDATE users SET account_state = $2, suspended_until = $3, updated_at = NOW()
+WHERE users.id = $1
+RETURNING *;
+-- name: AccountActive :one
+SELECT account_active($1)::boolean AS active;
+-- name: RevokeUserRefreshTokens :exec
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE refresh_tokens.user_id = $1 AND refresh_tokens.revoked_at IS NULL;
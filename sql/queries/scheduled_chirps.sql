@@ -0,0 +1,42 @@
+-- name: CountScheduledChirps :one
+SELECT COUNT(*) FROM chirps
+WHERE chirps.user_id = $1 AND chirps.publish_at IS NOT NULL;
+-- name: GetScheduledChirps :many
+SELECT * FROM chirps
+WHERE chirps.user_id = $1 AND chirps.publish_at IS NOT NULL
+ORDER BY chirps.publish_at, chirps.id;
+-- name: UpdateScheduledChirp :one
+UPDATE chirps
+SET body = COALESCE(sqlc.narg(body), body),
+    publish_at = COALESCE(sqlc.narg(publish_at), publish_at),
+    -- the edit may have fixed whatever kept it from publishing
+    publish_attempts = 0,
+    next_attempt_at = NULL,
+    updated_at = NOW()
+WHERE chirps.id = sqlc.arg(id) AND chirps.user_id = sqlc.arg(user_id) AND chirps.publish_at IS NOT NULL
+RETURNING *;
+-- name: CancelScheduledChirp :execrows
+DELETE FROM chirps
+WHERE chirps.id = $1 AND chirps.user_id = $2 AND chirps.publish_at IS NOT NULL;
+-- name: ClaimDueChirp :one
+-- Publishes the longest-overdue scheduled chirp. The row stays locked until
+-- the caller's transaction ends, and SKIP LOCKED lets other instances move
+-- on to the next one instead of waiting. Chirps held for spam review wait
+-- for a moderator instead, and ones that failed wait out their backoff.
+UPDATE chirps
+SET publish_at = NULL, created_at = NOW(), updated_at = NOW()
+WHERE chirps.id = (
+    SELECT due.id FROM chirps due
+    WHERE due.publish_at <= NOW() AND due.hidden_at IS NULL
+    AND (due.next_attempt_at IS NULL OR due.next_attempt_at <= NOW())
+    ORDER BY due.publish_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING *;
+-- name: PostponeScheduledChirp :exec
+-- backs off exponentially from retry_seconds, up to max_retry_seconds
+UPDATE chirps
+SET publish_attempts = publish_attempts + 1,
+    next_attempt_at = NOW() + LEAST(sqlc.arg(retry_seconds)::integer * POWER(2, chirps.publish_attempts), sqlc.arg(max_retry_seconds)::integer) * INTERVAL '1 second'
+WHERE chirps.id = sqlc.arg(id) AND chirps.publish_at IS NOT NULL;
@@ -0,0 +1,36 @@
+-- name: SearchChirps :many
+-- Matches are ordered by ranked_at, their creation time pushed forward by up
+-- to boost_seconds for the most relevant, so a strong old match can outrank
+-- a weak new one. The order of a given chirp never changes, so it pages like
+-- any timeline. Without query text matches are ordered by recency alone.
+WITH terms AS (
+    SELECT websearch_to_tsquery('english', sqlc.arg(query)::text) AS query
+), matches AS (
+    SELECT chirps.id, chirps.body, terms.query,
+        (chirps.created_at + ts_rank_cd(chirps.search, terms.query, 32) * sqlc.arg(boost_seconds)::int * INTERVAL '1 second')::timestamp AS ranked_at
+    FROM chirps, terms
+    WHERE (sqlc.arg(query)::text = '' OR chirps.search @@ terms.query)
+    AND chirps.body <> ''
+    AND chirps.deleted_at IS NULL
+    AND chirps.publish_at IS NULL
+    AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid)
+    AND NOT is_muted(sqlc.arg(viewer_id)::uuid, chirps.user_id)
+    AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id)::uuid)
+    AND (sqlc.narg(since)::timestamp IS NULL OR chirps.created_at >= sqlc.narg(since)::timestamp)
+    AND (sqlc.narg(until)::timestamp IS NULL OR chirps.created_at < sqlc.narg(until)::timestamp)
+    AND (
+        SELECT COUNT(*) FROM chirp_hashtags
+        JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+        WHERE chirp_hashtags.chirp_id = chirps.id
+        AND hashtags.tag = ANY(sqlc.arg(tags)::text[])
+    ) = cardinality(sqlc.arg(tags)::text[])
+)
+-- control characters in the body are blanked so they can't pass for the
+-- highlight markers
+SELECT matches.id, matches.ranked_at,
+    ts_headline('english', translate(matches.body, chr(2) || chr(3), '  '), matches.query,
+        'HighlightAll=true, StartSel=' || chr(2) || ', StopSel=' || chr(3))::text AS headline
+FROM matches
+WHERE (matches.ranked_at, matches.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY matches.ranked_at DESC, matches.id DESC
+LIMIT sqlc.arg(row_limit);
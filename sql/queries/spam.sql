@@ -0,0 +1,38 @@
+-- name: GetRecentChirpBodies :many
+-- exclude_id is the chirp being edited, which isn't a copy of itself
+SELECT chirps.body FROM chirps
+WHERE chirps.user_id = sqlc.arg(user_id) AND chirps.id <> sqlc.arg(exclude_id)::uuid
+AND chirps.deleted_at IS NULL AND chirps.rechirp_of_id IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(row_limit);
+-- name: CountChirpsSince :one
+SELECT COUNT(*) FROM chirps
+WHERE chirps.user_id = sqlc.arg(user_id) AND chirps.id <> sqlc.arg(exclude_id)::uuid
+AND chirps.created_at >= sqlc.arg(created_at);
+-- name: CreateSpamCheck :exec
+INSERT INTO spam_checks (id, user_id, chirp_id, body, score, action, signals, review, published, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, $8, NOW());
+-- name: GetSpamCheck :one
+SELECT * FROM spam_checks WHERE spam_checks.id = $1;
+-- name: GetSpamChecks :many
+-- action narrows the list to one verdict and pending to held chirps nobody
+-- has reviewed yet
+SELECT * FROM spam_checks
+WHERE (sqlc.narg(action)::text IS NULL OR spam_checks.action = sqlc.narg(action))
+AND (NOT sqlc.arg(pending)::boolean OR spam_checks.review = 'pending')
+AND (spam_checks.created_at, spam_checks.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY spam_checks.created_at DESC, spam_checks.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: ReviewSpamCheck :one
+UPDATE spam_checks
+SET review = $2, reviewed_by = $3, reviewed_at = NOW()
+WHERE spam_checks.id = $1 AND spam_checks.review = 'pending'
+RETURNING *;
+-- name: ChirpHeld :one
+-- chirps held when posted were never published, so nothing was counted for
+-- them
+SELECT EXISTS (
+    SELECT 1 FROM spam_checks
+    WHERE spam_checks.chirp_id = $1 AND spam_checks.review = 'pending'
+    AND NOT spam_checks.published
+) AS held;
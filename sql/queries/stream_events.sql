@@ -0,0 +1,22 @@
+-- name: CreateStreamEvent :exec
+INSERT INTO stream_events (type, chirp_id, notification_id, message_id, topics, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW());
+-- name: NotifyStreamEvent :exec
+-- wakes the relay on every instance once the surrounding transaction commits
+SELECT pg_notify('stream_events', '');
+-- name: GetLatestStreamEventID :one
+SELECT COALESCE(MAX(id), 0)::bigint AS id FROM stream_events;
+-- name: GetStreamEventsAfter :many
+SELECT * FROM stream_events
+WHERE stream_events.id > $1
+ORDER BY stream_events.id
+LIMIT $2;
+-- name: GetTopicStreamEventsAfter :many
+SELECT * FROM stream_events
+WHERE stream_events.id > sqlc.arg(after_id)
+AND stream_events.topics && sqlc.arg(topics)::text[]
+ORDER BY stream_events.id
+LIMIT sqlc.arg(row_limit);
+-- name: DeleteStreamEventsBefore :exec
+DELETE FROM stream_events
+WHERE stream_events.created_at < NOW() - sqlc.arg(age_seconds)::integer * INTERVAL '1 second';
@@ -0,0 +1,37 @@
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.id, parent.in_reply_to_id, 1 AS depth
+    FROM chirps child
+    JOIN chirps parent ON parent.id = child.in_reply_to_id
+    WHERE child.id = sqlc.arg(id)
+    UNION ALL
+    SELECT chirps.id, chirps.in_reply_to_id, ancestors.depth + 1
+    FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.in_reply_to_id
+)
+SELECT chirps.* FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+WHERE can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid)
+ORDER BY ancestors.depth DESC;
+-- name: GetChirpDescendants :many
+-- Depth-first walk of the reply tree. Each row's path holds one sortable
+-- "<created_at><id>" element per level, so ordering by path yields replies
+-- in tree order with siblings oldest first, and a path is a stable cursor.
+-- Replies the viewer can't see are left out along with everything below them.
+WITH RECURSIVE descendants AS (
+    SELECT chirps.id, 1 AS depth, ARRAY[to_char(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text] AS path
+    FROM chirps
+    WHERE chirps.in_reply_to_id = sqlc.arg(chirp_id)::uuid AND chirps.publish_at IS NULL
+    AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid)
+    UNION ALL
+    SELECT chirps.id, descendants.depth + 1, descendants.path || (to_char(chirps.created_at, 'YYYYMMDDHH24MISSUS') || chirps.id::text)
+    FROM chirps
+    JOIN descendants ON chirps.in_reply_to_id = descendants.id
+    WHERE descendants.depth < sqlc.arg(max_depth)::int AND chirps.publish_at IS NULL
+    AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(viewer_id)::uuid)
+)
+SELECT descendants.id, descendants.depth::int AS depth, descendants.path::text[] AS path
+FROM descendants
+WHERE descendants.path > sqlc.arg(after_path)::text[]
+ORDER BY descendants.path
+LIMIT sqlc.arg(row_limit);
@@ -0,0 +1,42 @@
+-- name: GetHomeTimeline :many
+SELECT chirps.* FROM chirps
+WHERE (chirps.user_id = sqlc.arg(user_id) OR chirps.user_id IN (
+    SELECT follows.followee_id FROM follows WHERE follows.follower_id = sqlc.arg(user_id)
+))
+AND chirps.deleted_at IS NULL
+AND chirps.publish_at IS NULL
+AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(user_id)::uuid)
+AND NOT is_muted(sqlc.arg(user_id)::uuid, chirps.user_id)
+AND (chirps.created_at, chirps.id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: GetMaterializedTimeline :many
+SELECT chirps.* FROM timeline_entries
+JOIN chirps ON chirps.id = timeline_entries.chirp_id
+WHERE timeline_entries.user_id = sqlc.arg(user_id)
+AND chirps.deleted_at IS NULL
+AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, sqlc.arg(user_id)::uuid)
+AND NOT is_muted(sqlc.arg(user_id)::uuid, chirps.user_id)
+AND (timeline_entries.created_at, timeline_entries.chirp_id) < (sqlc.arg(before_time)::timestamp, sqlc.arg(before_id)::uuid)
+ORDER BY timeline_entries.created_at DESC, timeline_entries.chirp_id DESC
+LIMIT sqlc.arg(row_limit);
+-- name: FanOutChirp :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT follows.follower_id, sqlc.arg(chirp_id)::uuid, sqlc.arg(author_id)::uuid, sqlc.arg(created_at)::timestamp
+FROM follows
+WHERE follows.followee_id = sqlc.arg(author_id)::uuid
+UNION ALL
+SELECT sqlc.arg(author_id)::uuid, sqlc.arg(chirp_id)::uuid, sqlc.arg(author_id)::uuid, sqlc.arg(created_at)::timestamp
+ON CONFLICT DO NOTHING;
+-- name: BackfillTimeline :exec
+INSERT INTO timeline_entries (user_id, chirp_id, author_id, created_at)
+SELECT sqlc.arg(user_id)::uuid, chirps.id, chirps.user_id, COALESCE(chirps.created_at, NOW())
+FROM chirps
+WHERE chirps.user_id = sqlc.arg(author_id)::uuid
+AND chirps.publish_at IS NULL
+ORDER BY chirps.created_at DESC
+LIMIT sqlc.arg(row_limit)
+ON CONFLICT DO NOTHING;
+-- name: RemoveAuthorFromTimeline :exec
+DELETE FROM timeline_entries
+WHERE user_id = $1 AND author_id = $2;
@@ -1,11 +1,13 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle, display_name)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4
 )
 RETURNING *;
 -- name: DeleteUsers :exec
@@ -13,4 +15,23 @@ DELETE FROM users;
 -- name: GetUserByEmail :one
 SELECT * FROM users
 WHERE users.email = $1;
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE users.id = $1;
+-- name: GetUserByHandle :one
+SELECT * FROM users
+WHERE users.handle = sqlc.arg(handle)::text;
+-- name: GetUserSummaries :many
+SELECT id, handle, display_name, avatar_key FROM users
+WHERE users.id = ANY(sqlc.arg(ids)::uuid[]);
+-- name: UpdateUserProfile :one
+UPDATE users
+SET handle = $2, display_name = $3, bio = $4, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+-- name: UpdateUserAvatar :one
+UPDATE users
+SET avatar_key = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
 -- name: UpdateEmailPassword :one
\ No newline at end of file
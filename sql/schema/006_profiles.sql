@@ -0,0 +1,12 @@
+-- +goose up
+ALTER TABLE users
+ADD handle TEXT UNIQUE,
+ADD display_name TEXT NOT NULL DEFAULT '',
+ADD bio TEXT NOT NULL DEFAULT '',
+ADD avatar_key TEXT;
+-- +goose down
+ALTER TABLE users
+DROP handle,
+DROP display_name,
+DROP bio,
+DROP avatar_key;
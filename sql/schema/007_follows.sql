@@ -0,0 +1,32 @@
+-- +goose up
+CREATE TABLE follows(
+     follower_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     followee_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     created_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (follower_id, followee_id),
+     CHECK (follower_id <> followee_id)
+);
+CREATE INDEX follows_followee_idx ON follows (followee_id, created_at);
+CREATE INDEX chirps_user_created_idx ON chirps (user_id, created_at);
+CREATE TABLE timeline_entries(
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     chirp_id uuid NOT NULL
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     author_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     created_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (user_id, chirp_id)
+);
+CREATE INDEX timeline_entries_user_created_idx ON timeline_entries (user_id, created_at, chirp_id);
+-- +goose down
+DROP TABLE timeline_entries;
+DROP INDEX chirps_user_created_idx;
+DROP TABLE follows;
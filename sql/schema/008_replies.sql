@@ -0,0 +1,18 @@
+-- +goose up
+ALTER TABLE chirps
+ADD in_reply_to_id uuid
+REFERENCES chirps
+ON DELETE SET NULL,
+ADD conversation_id uuid,
+ADD reply_count INTEGER NOT NULL DEFAULT 0,
+ADD deleted_at TIMESTAMP;
+CREATE INDEX chirps_in_reply_to_idx ON chirps (in_reply_to_id, created_at);
+CREATE INDEX chirps_conversation_idx ON chirps (conversation_id);
+-- +goose down
+DROP INDEX chirps_conversation_idx;
+DROP INDEX chirps_in_reply_to_idx;
+ALTER TABLE chirps
+DROP in_reply_to_id,
+DROP conversation_id,
+DROP reply_count,
+DROP deleted_at;
@@ -0,0 +1,18 @@
+-- +goose up
+CREATE TABLE chirp_likes(
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     chirp_id uuid NOT NULL
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     created_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (user_id, chirp_id)
+);
+CREATE INDEX chirp_likes_user_created_idx ON chirp_likes (user_id, created_at);
+ALTER TABLE chirps
+ADD like_count INTEGER NOT NULL DEFAULT 0;
+-- +goose down
+ALTER TABLE chirps
+DROP like_count;
+DROP TABLE chirp_likes;
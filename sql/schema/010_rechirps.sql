@@ -0,0 +1,17 @@
+-- +goose up
+ALTER TABLE chirps
+ADD rechirp_of_id uuid
+REFERENCES chirps
+ON DELETE CASCADE,
+ADD quote_of_id uuid
+REFERENCES chirps
+ON DELETE SET NULL,
+ADD rechirp_count INTEGER NOT NULL DEFAULT 0;
+CREATE UNIQUE INDEX chirps_one_rechirp_idx ON chirps (user_id, rechirp_of_id)
+WHERE rechirp_of_id IS NOT NULL AND deleted_at IS NULL;
+-- +goose down
+DROP INDEX chirps_one_rechirp_idx;
+ALTER TABLE chirps
+DROP rechirp_of_id,
+DROP quote_of_id,
+DROP rechirp_count;
@@ -0,0 +1,31 @@
+-- +goose up
+CREATE TABLE hashtags(
+     id uuid PRIMARY KEY,
+     tag TEXT UNIQUE NOT NULL,
+     created_at TIMESTAMP NOT NULL
+);
+CREATE TABLE chirp_hashtags(
+     chirp_id uuid NOT NULL
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     hashtag_id uuid NOT NULL
+     REFERENCES hashtags
+     ON DELETE CASCADE,
+     created_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (chirp_id, hashtag_id)
+);
+CREATE INDEX chirp_hashtags_hashtag_created_idx ON chirp_hashtags (hashtag_id, created_at);
+CREATE INDEX chirp_hashtags_created_idx ON chirp_hashtags (created_at);
+CREATE TABLE trending_hashtags(
+     window_name TEXT NOT NULL,
+     rank INTEGER NOT NULL,
+     tag TEXT NOT NULL,
+     chirp_count INTEGER NOT NULL,
+     score DOUBLE PRECISION NOT NULL,
+     computed_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (window_name, rank)
+);
+-- +goose down
+DROP TABLE trending_hashtags;
+DROP TABLE chirp_hashtags;
+DROP TABLE hashtags;
@@ -0,0 +1,31 @@
+-- +goose up
+CREATE TABLE chirp_mentions(
+     chirp_id uuid NOT NULL
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     handle TEXT NOT NULL,
+     PRIMARY KEY (chirp_id, user_id)
+);
+CREATE INDEX chirp_mentions_user_idx ON chirp_mentions (user_id);
+CREATE TABLE notifications(
+     id uuid PRIMARY KEY,
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     actor_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     type TEXT NOT NULL,
+     chirp_id uuid
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     created_at TIMESTAMP NOT NULL,
+     read_at TIMESTAMP
+);
+CREATE INDEX notifications_user_created_idx ON notifications (user_id, created_at);
+-- +goose down
+DROP TABLE notifications;
+DROP TABLE chirp_mentions;
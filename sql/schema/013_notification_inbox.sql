@@ -0,0 +1,51 @@
+-- +goose up
+ALTER TABLE notifications ADD COLUMN group_key TEXT;
+UPDATE notifications SET group_key = type || ':' || COALESCE(chirp_id::text, '');
+ALTER TABLE notifications ALTER COLUMN group_key SET NOT NULL;
+ALTER TABLE notifications ADD COLUMN updated_at TIMESTAMP;
+UPDATE notifications SET updated_at = created_at;
+ALTER TABLE notifications ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE notifications ADD COLUMN actor_count INTEGER NOT NULL DEFAULT 1;
+CREATE TABLE notification_actors(
+     notification_id uuid NOT NULL
+     REFERENCES notifications
+     ON DELETE CASCADE,
+     actor_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     created_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (notification_id, actor_id)
+);
+INSERT INTO notification_actors (notification_id, actor_id, created_at)
+SELECT id, actor_id, created_at FROM notifications;
+ALTER TABLE notifications DROP COLUMN actor_id;
+DROP INDEX notifications_user_created_idx;
+CREATE INDEX notifications_user_updated_idx ON notifications (user_id, updated_at, id);
+-- similar events collapse into the recipient's unread notification for the same group
+CREATE UNIQUE INDEX notifications_unread_group_idx ON notifications (user_id, group_key) WHERE read_at IS NULL;
+CREATE TABLE notification_preferences(
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     type TEXT NOT NULL,
+     enabled BOOLEAN NOT NULL,
+     PRIMARY KEY (user_id, type)
+);
+-- +goose down
+DROP TABLE notification_preferences;
+DROP INDEX notifications_unread_group_idx;
+DROP INDEX notifications_user_updated_idx;
+ALTER TABLE notifications ADD COLUMN actor_id uuid REFERENCES users ON DELETE CASCADE;
+UPDATE notifications SET actor_id = (
+     SELECT actor_id FROM notification_actors
+     WHERE notification_actors.notification_id = notifications.id
+     ORDER BY created_at DESC
+     LIMIT 1
+);
+DELETE FROM notifications WHERE actor_id IS NULL;
+ALTER TABLE notifications ALTER COLUMN actor_id SET NOT NULL;
+DROP TABLE notification_actors;
+ALTER TABLE notifications DROP COLUMN actor_count;
+ALTER TABLE notifications DROP COLUMN updated_at;
+ALTER TABLE notifications DROP COLUMN group_key;
+CREATE INDEX notifications_user_created_idx ON notifications (user_id, created_at);
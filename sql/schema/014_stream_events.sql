@@ -0,0 +1,13 @@
+-- +goose up
+CREATE TABLE stream_events(
+     id BIGSERIAL PRIMARY KEY,
+     type TEXT NOT NULL,
+     chirp_id uuid NOT NULL
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     topics TEXT[] NOT NULL,
+     created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX stream_events_created_idx ON stream_events (created_at);
+-- +goose down
+DROP TABLE stream_events;
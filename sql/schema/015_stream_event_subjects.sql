@@ -0,0 +1,9 @@
+-- +goose up
+ALTER TABLE stream_events ALTER COLUMN chirp_id DROP NOT NULL;
+ALTER TABLE stream_events ADD COLUMN notification_id uuid
+     REFERENCES notifications
+     ON DELETE CASCADE;
+-- +goose down
+DELETE FROM stream_events WHERE chirp_id IS NULL;
+ALTER TABLE stream_events DROP COLUMN notification_id;
+ALTER TABLE stream_events ALTER COLUMN chirp_id SET NOT NULL;
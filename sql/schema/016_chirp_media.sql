@@ -0,0 +1,25 @@
+-- +goose up
+CREATE TABLE chirp_media(
+     id uuid PRIMARY KEY,
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     -- NULL until the upload is attached to a chirp
+     chirp_id uuid
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     position INTEGER NOT NULL DEFAULT 0,
+     kind TEXT NOT NULL CHECK (kind IN ('image', 'gif')),
+     content_type TEXT NOT NULL,
+     original_key TEXT NOT NULL,
+     thumbnail_key TEXT NOT NULL,
+     width INTEGER NOT NULL,
+     height INTEGER NOT NULL,
+     size_bytes BIGINT NOT NULL,
+     alt_text TEXT NOT NULL DEFAULT '',
+     created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX chirp_media_chirp_idx ON chirp_media (chirp_id, position);
+CREATE INDEX chirp_media_unattached_idx ON chirp_media (created_at) WHERE chirp_id IS NULL;
+-- +goose down
+DROP TABLE chirp_media;
@@ -0,0 +1,28 @@
+-- +goose up
+CREATE TABLE link_previews(
+     url TEXT PRIMARY KEY,
+     status TEXT NOT NULL CHECK (status IN ('pending', 'ok', 'failed')),
+     title TEXT NOT NULL DEFAULT '',
+     description TEXT NOT NULL DEFAULT '',
+     image_url TEXT NOT NULL DEFAULT '',
+     site_name TEXT NOT NULL DEFAULT '',
+     attempts INTEGER NOT NULL DEFAULT 0,
+     -- pending rows are picked up once this passes; claiming pushes it out as a lease
+     next_attempt_at TIMESTAMP NOT NULL,
+     fetched_at TIMESTAMP,
+     created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX link_previews_pending_idx ON link_previews (next_attempt_at) WHERE status = 'pending';
+CREATE TABLE chirp_links(
+     chirp_id uuid NOT NULL
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     url TEXT NOT NULL
+     REFERENCES link_previews
+     ON DELETE CASCADE,
+     position INTEGER NOT NULL,
+     PRIMARY KEY (chirp_id, url)
+);
+-- +goose down
+DROP TABLE chirp_links;
+DROP TABLE link_previews;
@@ -0,0 +1,9 @@
+-- +goose up
+-- set while a chirp is waiting to be published, cleared by the scheduler
+ALTER TABLE chirps
+ADD publish_at TIMESTAMP;
+CREATE INDEX chirps_publish_at_idx ON chirps (publish_at) WHERE publish_at IS NOT NULL;
+-- +goose down
+DROP INDEX chirps_publish_at_idx;
+ALTER TABLE chirps
+DROP publish_at;
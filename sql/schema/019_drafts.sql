@@ -0,0 +1,17 @@
+-- +goose up
+-- references aren't foreign keys: a draft replying to a deleted chirp should
+-- fail to publish rather than silently become a standalone chirp
+CREATE TABLE drafts(
+     id uuid PRIMARY KEY,
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     body TEXT NOT NULL DEFAULT '',
+     in_reply_to_id uuid,
+     quote_of_id uuid,
+     created_at TIMESTAMP NOT NULL,
+     updated_at TIMESTAMP NOT NULL
+);
+CREATE INDEX drafts_user_idx ON drafts (user_id, updated_at DESC);
+-- +goose down
+DROP TABLE drafts;
@@ -0,0 +1,18 @@
+-- +goose up
+ALTER TABLE chirps
+ADD edited_at TIMESTAMP;
+-- every version of a chirp's body before its latest edit
+CREATE TABLE chirp_revisions(
+     id uuid PRIMARY KEY,
+     chirp_id uuid NOT NULL
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     body TEXT NOT NULL,
+     -- when this version was written
+     created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX chirp_revisions_chirp_idx ON chirp_revisions (chirp_id, created_at);
+-- +goose down
+DROP TABLE chirp_revisions;
+ALTER TABLE chirps
+DROP edited_at;
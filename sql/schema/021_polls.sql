@@ -0,0 +1,40 @@
+-- +goose up
+CREATE TABLE polls(
+     chirp_id uuid PRIMARY KEY
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     duration_seconds INTEGER NOT NULL,
+     -- NULL until the chirp is published, so scheduled polls run for their full duration
+     closes_at TIMESTAMP,
+     -- set by the background job once closes_at has passed
+     closed_at TIMESTAMP,
+     total_votes INTEGER NOT NULL DEFAULT 0,
+     created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX polls_closes_at_idx ON polls (closes_at) WHERE closed_at IS NULL;
+CREATE TABLE poll_options(
+     chirp_id uuid NOT NULL
+     REFERENCES polls
+     ON DELETE CASCADE,
+     position INTEGER NOT NULL,
+     text TEXT NOT NULL,
+     vote_count INTEGER NOT NULL DEFAULT 0,
+     PRIMARY KEY (chirp_id, position)
+);
+-- the primary key is what limits each user to one vote per poll
+CREATE TABLE poll_votes(
+     chirp_id uuid NOT NULL
+     REFERENCES polls
+     ON DELETE CASCADE,
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     position INTEGER NOT NULL,
+     created_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (chirp_id, user_id),
+     FOREIGN KEY (chirp_id, position) REFERENCES poll_options
+);
+-- +goose down
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
@@ -0,0 +1,24 @@
+-- +goose up
+ALTER TABLE chirps
+ADD visibility TEXT NOT NULL DEFAULT 'public' CHECK (visibility IN ('public', 'followers', 'mentioned'));
+-- The single definition of who may read a chirp, used by every query that
+-- lists chirps. Mentioned users can always see a chirp that mentions them.
+-- +goose StatementBegin
+CREATE FUNCTION can_view_chirp(author uuid, audience text, chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT audience = 'public'
+    OR author = viewer
+    OR (audience = 'followers' AND EXISTS (
+        SELECT 1 FROM follows
+        WHERE follows.follower_id = viewer AND follows.followee_id = author
+    ))
+    OR EXISTS (
+        SELECT 1 FROM chirp_mentions
+        WHERE chirp_mentions.chirp_id = chirp AND chirp_mentions.user_id = viewer
+    )
+$$;
+-- +goose StatementEnd
+-- +goose down
+DROP FUNCTION can_view_chirp;
+ALTER TABLE chirps
+DROP visibility;
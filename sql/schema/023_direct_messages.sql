@@ -0,0 +1,49 @@
+-- +goose up
+CREATE TABLE conversations(
+     id uuid PRIMARY KEY,
+     -- "<user id>:<user id>" in sorted order for one-to-one conversations, so
+     -- each pair of users shares a single conversation; NULL for groups
+     direct_key TEXT UNIQUE,
+     title TEXT NOT NULL DEFAULT '',
+     creator_id uuid
+     REFERENCES users
+     ON DELETE SET NULL,
+     created_at TIMESTAMP NOT NULL,
+     -- time of the latest message, for ordering the inbox
+     updated_at TIMESTAMP NOT NULL
+);
+CREATE TABLE conversation_members(
+     conversation_id uuid NOT NULL
+     REFERENCES conversations
+     ON DELETE CASCADE,
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     joined_at TIMESTAMP NOT NULL,
+     last_read_at TIMESTAMP NOT NULL,
+     left_at TIMESTAMP,
+     PRIMARY KEY (conversation_id, user_id)
+);
+CREATE INDEX conversation_members_user_idx ON conversation_members (user_id) WHERE left_at IS NULL;
+CREATE TABLE messages(
+     id uuid PRIMARY KEY,
+     conversation_id uuid NOT NULL
+     REFERENCES conversations
+     ON DELETE CASCADE,
+     sender_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     body TEXT NOT NULL,
+     created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX messages_conversation_idx ON messages (conversation_id, created_at DESC, id DESC);
+ALTER TABLE stream_events
+ADD message_id uuid
+REFERENCES messages
+ON DELETE CASCADE;
+-- +goose down
+ALTER TABLE stream_events
+DROP message_id;
+DROP TABLE messages;
+DROP TABLE conversation_members;
+DROP TABLE conversations;
@@ -0,0 +1,79 @@
+-- +goose up
+CREATE TABLE blocks(
+     blocker_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     blocked_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     created_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (blocker_id, blocked_id)
+);
+CREATE INDEX blocks_blocked_idx ON blocks (blocked_id);
+CREATE TABLE mutes(
+     muter_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     muted_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     created_at TIMESTAMP NOT NULL,
+     PRIMARY KEY (muter_id, muted_id)
+);
+-- A block cuts both ways: neither user sees the other's chirps.
+-- +goose StatementBegin
+CREATE FUNCTION is_blocked(a uuid, b uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM blocks
+        WHERE (blocks.blocker_id = a AND blocks.blocked_id = b)
+        OR (blocks.blocker_id = b AND blocks.blocked_id = a)
+    )
+$$;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE FUNCTION is_muted(muter uuid, muted uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM mutes
+        WHERE mutes.muter_id = muter AND mutes.muted_id = muted
+    )
+$$;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION can_view_chirp(author uuid, audience text, chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT author = viewer
+    OR (NOT is_blocked(author, viewer) AND (
+        audience = 'public'
+        OR (audience = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer AND follows.followee_id = author
+        ))
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions
+            WHERE chirp_mentions.chirp_id = chirp AND chirp_mentions.user_id = viewer
+        )
+    ))
+$$;
+-- +goose StatementEnd
+-- +goose down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION can_view_chirp(author uuid, audience text, chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT audience = 'public'
+    OR author = viewer
+    OR (audience = 'followers' AND EXISTS (
+        SELECT 1 FROM follows
+        WHERE follows.follower_id = viewer AND follows.followee_id = author
+    ))
+    OR EXISTS (
+        SELECT 1 FROM chirp_mentions
+        WHERE chirp_mentions.chirp_id = chirp AND chirp_mentions.user_id = viewer
+    )
+$$;
+-- +goose StatementEnd
+DROP FUNCTION is_muted;
+DROP FUNCTION is_blocked;
+DROP TABLE mutes;
+DROP TABLE blocks;
@@ -0,0 +1,15 @@
+-- +goose up
+CREATE TABLE muted_words(
+     id uuid PRIMARY KEY,
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     kind TEXT NOT NULL CHECK (kind IN ('word', 'phrase', 'hashtag', 'regex')),
+     pattern TEXT NOT NULL,
+     action TEXT NOT NULL CHECK (action IN ('hide', 'warn')),
+     expires_at TIMESTAMP,
+     created_at TIMESTAMP NOT NULL,
+     UNIQUE (user_id, kind, pattern)
+);
+-- +goose down
+DROP TABLE muted_words;
@@ -0,0 +1,101 @@
+-- +goose up
+-- moderators are appointed in psql: UPDATE users SET is_moderator = true WHERE ...
+ALTER TABLE users
+ADD is_moderator BOOLEAN NOT NULL DEFAULT false,
+ADD suspended_until TIMESTAMP;
+-- set when a chirp is hidden pending review, by report volume or a moderator
+ALTER TABLE chirps
+ADD hidden_at TIMESTAMP;
+CREATE TABLE reports(
+     id uuid PRIMARY KEY,
+     reporter_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     -- the reported user; the chirp's author for chirp reports
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     chirp_id uuid
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     reason TEXT NOT NULL CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'self_harm', 'sexual', 'misinformation', 'impersonation', 'other')),
+     details TEXT NOT NULL DEFAULT '',
+     status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+     resolution TEXT CHECK (resolution IN ('dismiss', 'remove_chirp', 'suspend_user', 'warn')),
+     assigned_to uuid
+     REFERENCES users
+     ON DELETE SET NULL,
+     resolved_by uuid
+     REFERENCES users
+     ON DELETE SET NULL,
+     created_at TIMESTAMP NOT NULL,
+     resolved_at TIMESTAMP
+);
+-- one open report per reporter and target
+CREATE UNIQUE INDEX reports_open_chirp_idx ON reports (reporter_id, chirp_id) WHERE status = 'open' AND chirp_id IS NOT NULL;
+CREATE UNIQUE INDEX reports_open_user_idx ON reports (reporter_id, user_id) WHERE status = 'open' AND chirp_id IS NULL;
+CREATE INDEX reports_queue_idx ON reports (status, created_at DESC, id DESC);
+-- the audit trail; rows outlive the reports, chirps and users they mention
+CREATE TABLE moderation_actions(
+     id uuid PRIMARY KEY,
+     -- NULL for actions taken automatically
+     moderator_id uuid
+     REFERENCES users
+     ON DELETE SET NULL,
+     report_id uuid
+     REFERENCES reports
+     ON DELETE SET NULL,
+     action TEXT NOT NULL,
+     target_user_id uuid,
+     target_chirp_id uuid,
+     note TEXT NOT NULL DEFAULT '',
+     created_at TIMESTAMP NOT NULL
+);
+CREATE INDEX moderation_actions_created_idx ON moderation_actions (created_at DESC, id DESC);
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION can_view_chirp(author uuid, audience text, chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT author = viewer
+    OR (NOT is_blocked(author, viewer)
+    AND NOT EXISTS (
+        SELECT 1 FROM chirps
+        WHERE chirps.id = chirp AND chirps.hidden_at IS NOT NULL
+    )
+    AND (
+        audience = 'public'
+        OR (audience = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer AND follows.followee_id = author
+        ))
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions
+            WHERE chirp_mentions.chirp_id = chirp AND chirp_mentions.user_id = viewer
+        )
+    ))
+$$;
+-- +goose StatementEnd
+-- +goose down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION can_view_chirp(author uuid, audience text, chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT author = viewer
+    OR (NOT is_blocked(author, viewer) AND (
+        audience = 'public'
+        OR (audience = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer AND follows.followee_id = author
+        ))
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions
+            WHERE chirp_mentions.chirp_id = chirp AND chirp_mentions.user_id = viewer
+        )
+    ))
+$$;
+-- +goose StatementEnd
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+ALTER TABLE chirps
+DROP hidden_at;
+ALTER TABLE users
+DROP is_moderator,
+DROP suspended_until;
@@ -0,0 +1,74 @@
+-- +goose up
+-- a suspension lifts itself once suspended_until passes; limited accounts
+-- keep working but nobody else sees what they post
+ALTER TABLE users
+ADD account_state TEXT NOT NULL DEFAULT 'active' CHECK (account_state IN ('active', 'limited', 'suspended', 'banned'));
+UPDATE users SET account_state = 'suspended' WHERE suspended_until > NOW();
+ALTER TABLE reports
+DROP CONSTRAINT reports_resolution_check,
+ADD CONSTRAINT reports_resolution_check CHECK (resolution IN ('dismiss', 'warn', 'remove_chirp', 'limit_user', 'suspend_user', 'ban_user'));
+-- +goose StatementBegin
+CREATE FUNCTION account_active(account uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM users
+        WHERE users.id = account
+        AND (users.account_state = 'active'
+            OR (users.account_state = 'suspended' AND users.suspended_until <= NOW()))
+    )
+$$;
+-- +goose StatementEnd
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION can_view_chirp(author uuid, audience text, chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT author = viewer
+    OR (NOT is_blocked(author, viewer)
+    AND account_active(author)
+    AND NOT EXISTS (
+        SELECT 1 FROM chirps
+        WHERE chirps.id = chirp AND chirps.hidden_at IS NOT NULL
+    )
+    AND (
+        audience = 'public'
+        OR (audience = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer AND follows.followee_id = author
+        ))
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions
+            WHERE chirp_mentions.chirp_id = chirp AND chirp_mentions.user_id = viewer
+        )
+    ))
+$$;
+-- +goose StatementEnd
+-- +goose down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION can_view_chirp(author uuid, audience text, chirp uuid, viewer uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT author = viewer
+    OR (NOT is_blocked(author, viewer)
+    AND NOT EXISTS (
+        SELECT 1 FROM chirps
+        WHERE chirps.id = chirp AND chirps.hidden_at IS NOT NULL
+    )
+    AND (
+        audience = 'public'
+        OR (audience = 'followers' AND EXISTS (
+            SELECT 1 FROM follows
+            WHERE follows.follower_id = viewer AND follows.followee_id = author
+        ))
+        OR EXISTS (
+            SELECT 1 FROM chirp_mentions
+            WHERE chirp_mentions.chirp_id = chirp AND chirp_mentions.user_id = viewer
+        )
+    ))
+$$;
+-- +goose StatementEnd
+DROP FUNCTION account_active;
+UPDATE reports SET resolution = 'warn' WHERE resolution = 'limit_user';
+UPDATE reports SET resolution = 'suspend_user' WHERE resolution = 'ban_user';
+ALTER TABLE reports
+DROP CONSTRAINT reports_resolution_check,
+ADD CONSTRAINT reports_resolution_check CHECK (resolution IN ('dismiss', 'remove_chirp', 'suspend_user', 'warn'));
+ALTER TABLE users
+DROP account_state;
@@ -0,0 +1,28 @@
+-- +goose up
+-- the spam pipeline's verdict on each chirp it scored above zero
+CREATE TABLE spam_checks(
+     id uuid PRIMARY KEY,
+     user_id uuid NOT NULL
+     REFERENCES users
+     ON DELETE CASCADE,
+     -- NULL for rejected chirps, which are never created
+     chirp_id uuid
+     REFERENCES chirps
+     ON DELETE CASCADE,
+     body TEXT NOT NULL,
+     score DOUBLE PRECISION NOT NULL,
+     action TEXT NOT NULL CHECK (action IN ('allow', 'verify', 'hold', 'reject')),
+     -- [{"check": ..., "score": ..., "reason": ...}]
+     signals JSONB NOT NULL,
+     -- pending while a held chirp waits for a moderator
+     review TEXT CHECK (review IN ('pending', 'approved', 'rejected')),
+     reviewed_by uuid
+     REFERENCES users
+     ON DELETE SET NULL,
+     created_at TIMESTAMP NOT NULL,
+     reviewed_at TIMESTAMP
+);
+CREATE INDEX spam_checks_created_idx ON spam_checks (created_at DESC, id DESC);
+CREATE INDEX spam_checks_chirp_idx ON spam_checks (chirp_id) WHERE review = 'pending';
+-- +goose down
+DROP TABLE spam_checks;
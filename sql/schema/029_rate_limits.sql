@@ -0,0 +1,10 @@
+-- +goose up
+-- token buckets shared by every instance; losing them in a crash only
+-- resets everyone's limits, so they skip the WAL
+CREATE UNLOGGED TABLE rate_limits(
+     key TEXT PRIMARY KEY,
+     tokens DOUBLE PRECISION NOT NULL,
+     updated_at TIMESTAMP NOT NULL
+);
+-- +goose down
+DROP TABLE rate_limits;
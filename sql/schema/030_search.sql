@@ -0,0 +1,9 @@
+-- +goose up
+ALTER TABLE chirps
+ADD search tsvector
+GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+CREATE INDEX chirps_search_idx ON chirps USING GIN (search);
+-- +goose down
+DROP INDEX chirps_search_idx;
+ALTER TABLE chirps
+DROP search;
@@ -0,0 +1,8 @@
+-- +goose up
+-- set when an edit to a chirp that was already published is checked, as
+-- such a chirp was counted and indexed before it was held
+ALTER TABLE spam_checks
+ADD published BOOLEAN NOT NULL DEFAULT false;
+-- +goose down
+ALTER TABLE spam_checks
+DROP published;
@@ -0,0 +1,22 @@
+-- +goose up
+-- A notification is listed and counted while its chirp, if it has one, is
+-- still there for the recipient to see, and someone it's from isn't blocked.
+-- +goose StatementBegin
+CREATE FUNCTION notification_visible(notification uuid, recipient uuid, chirp uuid) RETURNS boolean
+LANGUAGE sql STABLE AS $$
+    SELECT (chirp IS NULL OR EXISTS (
+        SELECT 1 FROM chirps
+        WHERE chirps.id = chirp
+        AND chirps.deleted_at IS NULL
+        AND chirps.publish_at IS NULL
+        AND can_view_chirp(chirps.user_id, chirps.visibility, chirps.id, recipient)
+    ))
+    AND EXISTS (
+        SELECT 1 FROM notification_actors
+        WHERE notification_actors.notification_id = notification
+        AND NOT is_blocked(recipient, notification_actors.actor_id)
+    )
+$$;
+-- +goose StatementEnd
+-- +goose down
+DROP FUNCTION notification_visible;
@@ -0,0 +1,10 @@
+-- +goose up
+-- the login a refresh token belongs to, named in the access tokens issued
+-- with it so that revoking it also ends that login's live connections
+ALTER TABLE refresh_tokens
+ADD session_id uuid NOT NULL DEFAULT gen_random_uuid();
+CREATE INDEX refresh_tokens_session_idx ON refresh_tokens (session_id);
+-- +goose down
+DROP INDEX refresh_tokens_session_idx;
+ALTER TABLE refresh_tokens
+DROP session_id;
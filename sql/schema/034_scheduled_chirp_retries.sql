@@ -0,0 +1,10 @@
+-- +goose up
+-- a scheduled chirp that fails to publish is set aside until next_attempt_at,
+-- so it can't hold up the ones due after it
+ALTER TABLE chirps
+ADD publish_attempts INTEGER NOT NULL DEFAULT 0,
+ADD next_attempt_at TIMESTAMP;
+-- +goose down
+ALTER TABLE chirps
+DROP publish_attempts,
+DROP next_attempt_at;
@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/entities"
+	"github.com/leiper-mike/chirpy/internal/stream"
+	"github.com/lib/pq"
+)
+
+const (
+	streamEventCreated        = "chirp_created"
+	streamEventDeleted        = "chirp_deleted"
+	streamEventEdited         = "chirp_edited"
+	streamEventNotification   = "notification"
+	streamEventMessage        = "message"
+	streamEventSessionRevoked = "session_revoked"
+
+	streamBufferSize  = 64
+	streamHeartbeat   = 15 * time.Second
+	streamReplayLimit = 500
+	streamRetention   = 24 * time.Hour
+	streamRelayBatch  = 200
+	streamRelayPing   = 90 * time.Second
+	// event IDs are taken when a transaction inserts but become visible when it
+	// commits, so the relay looks this far back for events that committed late
+	streamRelayLookback = 1000
+)
+
+const globalTopic = "global"
+
+func userTopic(userID uuid.UUID) string { return "user:" + userID.String() }
+func hashtagTopic(tag string) string    { return "hashtag:" + tag }
+func conversationTopic(conversationID uuid.UUID) string {
+	return "conversation:" + conversationID.String()
+}
+
+// Private topics are only ever subscribed to on behalf of their user.
+func notificationsTopic(userID uuid.UUID) string { return "notifications:" + userID.String() }
+func messagesTopic(userID uuid.UUID) string      { return "messages:" + userID.String() }
+
+// sessionTopic hears when one login is signed out, accountTopic when all of
+// a user's are.
+func sessionTopic(sessionID uuid.UUID) string { return "session:" + sessionID.String() }
+func accountTopic(userID uuid.UUID) string    { return "account:" + userID.String() }
+
+// chirpTopics lists every stream a chirp's events are published to.
+func chirpTopics(dbChirp database.Chirp) []string {
+	topics := []string{globalTopic, userTopic(dbChirp.UserID), conversationTopic(conversationID(dbChirp))}
+	for _, tag := range entities.Unique(entities.Hashtags(dbChirp.Body)) {
+		topics = append(topics, hashtagTopic(tag))
+	}
+	return topics
+}
+
+// publishEvent records an event for the stream. Listeners are woken when the
+// transaction q belongs to commits, so rolled back changes are never sent.
+func publishEvent(ctx context.Context, q *database.Queries, params database.CreateStreamEventParams) error {
+	err := q.CreateStreamEvent(ctx, params)
+	if err != nil {
+		return err
+	}
+	return q.NotifyStreamEvent(ctx)
+}
+
+// publishChirpEvent streams an event about a chirp. Only public chirps are
+// streamed, as one rendered message is sent to every subscriber of a topic.
+func publishChirpEvent(ctx context.Context, q *database.Queries, kind string, dbChirp database.Chirp) error {
+	if dbChirp.Visibility != visibilityPublic {
+		return nil
+	}
+	// nobody else sees hidden chirps or chirps from limited accounts, so
+	// they aren't pushed live either
+	if kind != streamEventDeleted {
+		if dbChirp.HiddenAt.Valid {
+			return nil
+		}
+		active, err := q.AccountActive(ctx, dbChirp.UserID)
+		if err != nil || !active {
+			return err
+		}
+	}
+	return publishEvent(ctx, q, database.CreateStreamEventParams{Type: kind, ChirpID: uuid.NullUUID{UUID: dbChirp.ID, Valid: true}, Topics: chirpTopics(dbChirp)})
+}
+
+// loadStreamEvents renders stored events into messages. Chirps are rendered
+// as an anonymous viewer sees them since one message goes to many clients;
+// notifications only go to their recipient and are rendered for them, and
+// messages only to the members of their conversation.
+func (cfg *apiConfig) loadStreamEvents(ctx context.Context, dbEvents []database.StreamEvent) ([]stream.Event, error) {
+	chirpIDs := []uuid.UUID{}
+	notificationIDs := []uuid.UUID{}
+	messageIDs := []uuid.UUID{}
+	for _, dbEvent := range dbEvents {
+		switch dbEvent.Type {
+		case streamEventCreated, streamEventEdited:
+			chirpIDs = append(chirpIDs, dbEvent.ChirpID.UUID)
+		case streamEventNotification:
+			notificationIDs = append(notificationIDs, dbEvent.NotificationID.UUID)
+		case streamEventMessage:
+			messageIDs = append(messageIDs, dbEvent.MessageID.UUID)
+		}
+	}
+	chirps, err := cfg.loadStreamChirps(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	notifications, err := cfg.loadStreamNotifications(ctx, notificationIDs)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := cfg.loadStreamMessages(ctx, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]stream.Event, 0, len(dbEvents))
+	for _, dbEvent := range dbEvents {
+		var payload interface{} = struct{}{}
+		switch dbEvent.Type {
+		case streamEventCreated, streamEventEdited:
+			chirp, ok := chirps[dbEvent.ChirpID.UUID]
+			if !ok {
+				continue
+			}
+			payload = chirp
+		case streamEventDeleted:
+			payload = map[string]uuid.UUID{"id": dbEvent.ChirpID.UUID}
+		case streamEventNotification:
+			notification, ok := notifications[dbEvent.NotificationID.UUID]
+			if !ok {
+				continue
+			}
+			payload = notification
+		case streamEventMessage:
+			m, ok := messages[dbEvent.MessageID.UUID]
+			if !ok {
+				continue
+			}
+			payload = m
+		}
+		dat, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, stream.Event{ID: dbEvent.ID, Type: dbEvent.Type, Data: dat, Topics: dbEvent.Topics})
+	}
+	return events, nil
+}
+
+func (cfg *apiConfig) loadStreamChirps(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Chirp, error) {
+	chirps := map[uuid.UUID]Chirp{}
+	if len(ids) == 0 {
+		return chirps, nil
+	}
+	dbChirps, err := cfg.dbQueries.GetVisibleChirpsByIDs(ctx, database.GetVisibleChirpsByIDsParams{Ids: ids, ViewerID: uuid.Nil})
+	if err != nil {
+		return nil, err
+	}
+	rendered, err := cfg.renderChirps(ctx, uuid.Nil, dbChirps)
+	if err != nil {
+		return nil, err
+	}
+	for _, chirp := range rendered {
+		chirps[chirp.ID] = chirp
+	}
+	return chirps, nil
+}
+
+func (cfg *apiConfig) loadStreamMessages(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Message, error) {
+	messages := map[uuid.UUID]Message{}
+	if len(ids) == 0 {
+		return messages, nil
+	}
+	dbMessages, err := cfg.dbQueries.GetMessagesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, dbMessage := range dbMessages {
+		messages[dbMessage.ID] = message(dbMessage)
+	}
+	return messages, nil
+}
+
+func (cfg *apiConfig) loadStreamNotifications(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]Notification, error) {
+	notifications := map[uuid.UUID]Notification{}
+	if len(ids) == 0 {
+		return notifications, nil
+	}
+	dbNotifications, err := cfg.dbQueries.GetNotificationsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byRecipient := map[uuid.UUID][]database.Notification{}
+	for _, dbNotification := range dbNotifications {
+		byRecipient[dbNotification.UserID] = append(byRecipient[dbNotification.UserID], dbNotification)
+	}
+	for recipient, list := range byRecipient {
+		rendered, err := cfg.renderNotifications(ctx, recipient, list)
+		if err != nil {
+			return nil, err
+		}
+		for _, notification := range rendered {
+			notifications[notification.ID] = notification
+		}
+	}
+	return notifications, nil
+}
+
+// runStreamRelay listens for NOTIFYs from any instance and publishes the new
+// events to this instance's broker.
+func (cfg *apiConfig) runStreamRelay(ctx context.Context, dbURL string) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			fmt.Printf("Stream listener: %v\n", err)
+		}
+	})
+	defer listener.Close()
+	err := listener.Listen("stream_events")
+	if err != nil {
+		fmt.Printf("Error listening for stream events: %v\n", err)
+	}
+	startID, err := cfg.dbQueries.GetLatestStreamEventID(ctx)
+	if err != nil {
+		fmt.Printf("Error getting latest stream event: %v\n", err)
+	}
+	relay := &streamRelay{cfg: cfg, startID: startID, lastID: startID, seen: map[int64]bool{}}
+	ping := time.NewTicker(streamRelayPing)
+	defer ping.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			// also catches up on anything missed while the connection was down
+			listener.Ping()
+		case <-prune.C:
+			err := cfg.dbQueries.DeleteStreamEventsBefore(ctx, int32(streamRetention.Seconds()))
+			if err != nil {
+				fmt.Printf("Error pruning stream events: %v\n", err)
+			}
+			continue
+		case <-listener.Notify:
+			// a nil notification means the connection was re-established
+		}
+		relay.catchUp(ctx)
+	}
+}
+
+// streamRelay tracks which events this instance has already published.
+// Events from before it started are never published.
+type streamRelay struct {
+	cfg     *apiConfig
+	startID int64
+	lastID  int64
+	seen    map[int64]bool
+}
+
+func (relay *streamRelay) catchUp(ctx context.Context) {
+	for {
+		after := max(relay.lastID-streamRelayLookback, relay.startID)
+		dbEvents, err := relay.cfg.dbQueries.GetStreamEventsAfter(ctx, database.GetStreamEventsAfterParams{ID: after, Limit: streamRelayBatch + streamRelayLookback})
+		if err != nil {
+			fmt.Printf("Error getting stream events: %v\n", err)
+			return
+		}
+		fresh := []database.StreamEvent{}
+		for _, dbEvent := range dbEvents {
+			if !relay.seen[dbEvent.ID] {
+				relay.seen[dbEvent.ID] = true
+				fresh = append(fresh, dbEvent)
+			}
+			relay.lastID = max(relay.lastID, dbEvent.ID)
+		}
+		for id := range relay.seen {
+			if id <= relay.lastID-streamRelayLookback {
+				delete(relay.seen, id)
+			}
+		}
+		events, err := relay.cfg.loadStreamEvents(ctx, fresh)
+		if err != nil {
+			fmt.Printf("Error loading stream events: %v\n", err)
+			return
+		}
+		for _, event := range events {
+			relay.cfg.broker.Publish(event)
+		}
+		if len(dbEvents) < streamRelayBatch+streamRelayLookback {
+			return
+		}
+	}
+}
+
+// streamTopics picks the topics a stream request follows from its filter. A
+// timeline stream covers the accounts followed when it was opened.
+func (cfg *apiConfig) streamTopics(r *http.Request) ([]string, error) {
+	query := r.URL.Query()
+	switch query.Get("filter") {
+	case "", "global":
+		return []string{globalTopic}, nil
+	case "user":
+		dbUser, err := cfg.dbQueries.GetUserByHandle(r.Context(), normalizeHandle(query.Get("handle")))
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return nil, &requestError{404, "User not found"}
+			}
+			return nil, err
+		}
+		return []string{userTopic(dbUser.ID)}, nil
+	case "hashtag":
+		tag := entities.NormalizeTag(query.Get("tag"))
+		if tag == "" {
+			return nil, &requestError{400, "A tag is required"}
+		}
+		return []string{hashtagTopic(tag)}, nil
+	case "timeline":
+		userID, err := cfg.authenticate(r)
+		if err != nil {
+			return nil, &requestError{401, "Unauthorized"}
+		}
+		return cfg.timelineTopics(r.Context(), userID)
+	}
+	return nil, &requestError{400, "filter must be one of global, user, hashtag or timeline"}
+}
+
+// timelineTopics follows the same authors as the home timeline, leaving out
+// anyone the user has muted.
+func (cfg *apiConfig) timelineTopics(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	followees, err := cfg.dbQueries.GetFolloweeIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	muted, err := cfg.dbQueries.GetMutedUserIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	topics := []string{userTopic(userID)}
+	for _, followee := range followees {
+		if !slices.Contains(muted, followee) {
+			topics = append(topics, userTopic(followee))
+		}
+	}
+	return topics, nil
+}
+
+// streamHandler sends chirp events as Server-Sent Events. Clients resuming
+// with Last-Event-ID first get the events they missed. A client that can't keep
+// up is disconnected and expected to reconnect the same way; one that is too
+// far behind to replay gets a reset event and should reload instead.
+func (cfg *apiConfig) streamHandler(w http.ResponseWriter, r *http.Request) {
+	topics, err := cfg.streamTopics(r)
+	if err != nil {
+		if reqErr, ok := err.(*requestError); ok {
+			respondWithText(w, reqErr.status, reqErr.msg)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	var lastID int64
+	if s := r.Header.Get("Last-Event-ID"); s != "" {
+		lastID, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			respondWithText(w, 400, "Invalid Last-Event-ID")
+			return
+		}
+	}
+	hidden, err := cfg.blockedTopics(r.Context(), cfg.viewerID(r))
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	// subscribe before replaying so nothing published in between is lost
+	sub := cfg.broker.Subscribe(topics)
+	defer sub.Close()
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	fmt.Fprintf(w, "retry: 2000\n\n")
+	if lastID > 0 {
+		dbEvents, err := cfg.dbQueries.GetTopicStreamEventsAfter(r.Context(), database.GetTopicStreamEventsAfterParams{AfterID: lastID, Topics: topics, RowLimit: streamReplayLimit})
+		if err != nil {
+			fmt.Printf("Error replaying stream events: %v\n", err)
+			return
+		}
+		if len(dbEvents) == streamReplayLimit {
+			fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+			rc.Flush()
+			return
+		}
+		events, err := cfg.loadStreamEvents(r.Context(), dbEvents)
+		if err != nil {
+			fmt.Printf("Error replaying stream events: %v\n", err)
+			return
+		}
+		for _, event := range events {
+			if !hiddenEvent(event, hidden) {
+				writeStreamEvent(w, event)
+			}
+		}
+		if len(dbEvents) > 0 {
+			lastID = dbEvents[len(dbEvents)-1].ID
+		}
+	}
+	if rc.Flush() != nil {
+		return
+	}
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprintf(w, ": heartbeat\n\n")
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if event.ID <= lastID || hiddenEvent(event, hidden) {
+				continue
+			}
+			writeStreamEvent(w, event)
+		}
+		if rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, event stream.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+const maxThreadDepth = 100
+
+type ThreadReply struct {
+	Chirp
+	Depth int32 `json:"depth"`
+}
+
+type Thread struct {
+	Chirp      Chirp         `json:"chirp"`
+	Ancestors  []Chirp       `json:"ancestors"`
+	Replies    []ThreadReply `json:"replies"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) threadHandler(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		w.WriteHeader(404)
+		return
+	}
+	_, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	// thread cursors are the tree path of the last reply returned
+	afterPath := []string{}
+	if s := r.URL.Query().Get("cursor"); s != "" {
+		dat, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			respondWithText(w, 400, "Invalid cursor")
+			return
+		}
+		afterPath = strings.Split(string(dat), ",")
+	}
+	dbChirp, err := cfg.dbQueries.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(404)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	viewerID := cfg.viewerID(r)
+	visible, err := canView(r.Context(), cfg.dbQueries, dbChirp, viewerID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if !visible {
+		w.WriteHeader(404)
+		return
+	}
+	ancestors, err := cfg.dbQueries.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{ID: chirpID, ViewerID: viewerID})
+	if err != nil {
+		fmt.Printf("Error getting ancestors:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	descendants, err := cfg.dbQueries.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{ChirpID: chirpID, ViewerID: viewerID, MaxDepth: maxThreadDepth, AfterPath: afterPath, RowLimit: limit})
+	if err != nil {
+		fmt.Printf("Error getting replies:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	replyIDs := make([]uuid.UUID, 0, len(descendants))
+	for _, d := range descendants {
+		replyIDs = append(replyIDs, d.ID)
+	}
+	replies, err := cfg.dbQueries.GetChirpsByIDs(r.Context(), replyIDs)
+	if err != nil {
+		fmt.Printf("Error getting replies:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	// render everything in one pass, then split it back up
+	all := append([]database.Chirp{dbChirp}, ancestors...)
+	all = append(all, replies...)
+	rendered, err := cfg.renderChirps(r.Context(), viewerID, all)
+	if err != nil {
+		fmt.Printf("Error rendering chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	byID := make(map[uuid.UUID]Chirp, len(rendered))
+	for _, chirp := range rendered {
+		byID[chirp.ID] = chirp
+	}
+	thread := Thread{Chirp: rendered[0], Ancestors: rendered[1 : 1+len(ancestors)], Replies: make([]ThreadReply, 0, len(descendants))}
+	for _, d := range descendants {
+		if chirp, ok := byID[d.ID]; ok {
+			thread.Replies = append(thread.Replies, ThreadReply{Chirp: chirp, Depth: d.Depth})
+		}
+	}
+	if len(descendants) == int(limit) {
+		last := descendants[len(descendants)-1]
+		thread.NextCursor = base64.RawURLEncoding.EncodeToString([]byte(strings.Join(last.Path, ",")))
+	}
+	respondWithJSON(w, 200, thread)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+// number of a followee's recent chirps copied into a materialized timeline on follow
+const timelineBackfillSize = 200
+
+type ChirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// timelineStrategy decides how home timelines are assembled. Fan-out-on-read
+// joins follows against chirps at request time; fan-out-on-write copies each
+// new chirp into a timeline_entries row per follower when it is posted.
+// Switching an existing deployment to fan-out-on-write only materializes
+// chirps posted (or follows made) after the switch.
+type timelineStrategy interface {
+	chirpCreated(ctx context.Context, q *database.Queries, chirp database.Chirp) error
+	followed(ctx context.Context, q *database.Queries, follower, followee uuid.UUID) error
+	unfollowed(ctx context.Context, q *database.Queries, follower, followee uuid.UUID) error
+	read(ctx context.Context, q *database.Queries, userID uuid.UUID, cursor pageCursor, limit int32) ([]database.Chirp, error)
+}
+
+func newTimelineStrategy(name string) (timelineStrategy, error) {
+	switch name {
+	case "", "read":
+		return fanOutOnRead{}, nil
+	case "write":
+		return fanOutOnWrite{}, nil
+	}
+	return nil, fmt.Errorf("unknown TIMELINE_STRATEGY %q, expected read or write", name)
+}
+
+type fanOutOnRead struct{}
+
+func (fanOutOnRead) chirpCreated(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	return nil
+}
+
+func (fanOutOnRead) followed(ctx context.Context, q *database.Queries, follower, followee uuid.UUID) error {
+	return nil
+}
+
+func (fanOutOnRead) unfollowed(ctx context.Context, q *database.Queries, follower, followee uuid.UUID) error {
+	return nil
+}
+
+func (fanOutOnRead) read(ctx context.Context, q *database.Queries, userID uuid.UUID, cursor pageCursor, limit int32) ([]database.Chirp, error) {
+	return q.GetHomeTimeline(ctx, database.GetHomeTimelineParams{UserID: userID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+}
+
+type fanOutOnWrite struct{}
+
+func (fanOutOnWrite) chirpCreated(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	return q.FanOutChirp(ctx, database.FanOutChirpParams{ChirpID: chirp.ID, AuthorID: chirp.UserID, CreatedAt: chirp.CreatedAt.Time})
+}
+
+func (fanOutOnWrite) followed(ctx context.Context, q *database.Queries, follower, followee uuid.UUID) error {
+	return q.BackfillTimeline(ctx, database.BackfillTimelineParams{UserID: follower, AuthorID: followee, RowLimit: timelineBackfillSize})
+}
+
+func (fanOutOnWrite) unfollowed(ctx context.Context, q *database.Queries, follower, followee uuid.UUID) error {
+	return q.RemoveAuthorFromTimeline(ctx, database.RemoveAuthorFromTimelineParams{UserID: follower, AuthorID: followee})
+}
+
+func (fanOutOnWrite) read(ctx context.Context, q *database.Queries, userID uuid.UUID, cursor pageCursor, limit int32) ([]database.Chirp, error) {
+	return q.GetMaterializedTimeline(ctx, database.GetMaterializedTimelineParams{UserID: userID, BeforeTime: cursor.Time, BeforeID: cursor.ID, RowLimit: limit})
+}
+
+func (cfg *apiConfig) timelineHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return
+	}
+	cursor, limit, err := parsePage(r)
+	if err != nil {
+		respondWithText(w, 400, err.Error())
+		return
+	}
+	dbChirps, err := cfg.timeline.read(r.Context(), cfg.dbQueries, userID, cursor, limit)
+	if err != nil {
+		fmt.Printf("Error getting timeline:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	cfg.respondWithChirpPage(w, r.Context(), userID, dbChirps, limit)
+}
+
+func (cfg *apiConfig) respondWithChirpPage(w http.ResponseWriter, ctx context.Context, viewerID uuid.UUID, dbChirps []database.Chirp, limit int32) {
+	chirps, err := cfg.renderChirps(ctx, viewerID, dbChirps)
+	if err != nil {
+		fmt.Printf("Error rendering chirps:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	mutedWords, err := cfg.mutedWordFilter(ctx, viewerID)
+	if err != nil {
+		fmt.Printf("Error loading muted words:%v\n", err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	// the cursor comes from the unfiltered rows so hidden chirps never stall paging
+	page := ChirpPage{Chirps: filterChirps(mutedWords, viewerID, chirps)}
+	if len(dbChirps) > 0 {
+		last := dbChirps[len(dbChirps)-1]
+		page.NextCursor = nextCursor(len(dbChirps), limit, pageCursor{Time: last.CreatedAt.Time, ID: last.ID})
+	}
+	respondWithJSON(w, 200, page)
+}
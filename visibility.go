@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/leiper-mike/chirpy/internal/database"
+)
+
+const (
+	visibilityPublic    = "public"
+	visibilityFollowers = "followers"
+	visibilityMentioned = "mentioned"
+)
+
+func validateVisibility(visibility string) (string, error) {
+	switch visibility {
+	case "":
+		return visibilityPublic, nil
+	case visibilityPublic, visibilityFollowers, visibilityMentioned:
+		return visibility, nil
+	}
+	return "", &requestError{400, "visibility must be public, followers or mentioned"}
+}
+
+// canView reports whether viewerID, or uuid.Nil for anonymous requests, may
+// see a chirp. Handlers respond 404 when it is false so that hidden chirps
+// are indistinguishable from missing ones. Queries listing chirps apply the
+// same rules through the can_view_chirp SQL function.
+func canView(ctx context.Context, q *database.Queries, dbChirp database.Chirp, viewerID uuid.UUID) (bool, error) {
+	if dbChirp.UserID == viewerID {
+		return true, nil
+	}
+	// scheduled chirps aren't out yet and hidden ones are waiting on a moderator
+	if dbChirp.PublishAt.Valid || dbChirp.HiddenAt.Valid {
+		return false, nil
+	}
+	// anonymous viewers only ever see public chirps; blocks and the author's
+	// account state are left to the query
+	if viewerID == uuid.Nil && dbChirp.Visibility != visibilityPublic {
+		return false, nil
+	}
+	return q.CanViewChirp(ctx, database.CanViewChirpParams{ViewerID: viewerID, ChirpID: dbChirp.ID})
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/leiper-mike/chirpy/internal/auth"
+	"github.com/leiper-mike/chirpy/internal/database"
+	"github.com/leiper-mike/chirpy/internal/stream"
+)
+
+const (
+	wsPingInterval = 30 * time.Second
+	wsPongWait     = 60 * time.Second
+	wsWriteWait    = 10 * time.Second
+	wsMaxMessage   = 4096
+	wsSendBuffer   = 64
+	wsMaxChannels  = 20
+	wsCloseExpired = 4001
+	wsCloseRevoked = 4002
+	wsCloseTooSlow = 4003
+	wsThreadPrefix = "thread:"
+)
+
+// Clients authenticate with a bearer token rather than cookies, so a page on
+// another origin gains nothing by opening a socket and any origin is allowed.
+var wsUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsClientMessage is sent by clients to manage subscriptions. Channels are
+// "timeline", "notifications", "messages" and "thread:<chirp id>". An auth
+// message carries a fresh access token to keep the connection open past the
+// current one's expiry.
+type wsClientMessage struct {
+	Type        string `json:"type"`
+	Channel     string `json:"channel"`
+	LastEventID int64  `json:"last_event_id"`
+	Token       string `json:"token"`
+}
+
+type wsServerMessage struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	ID      int64           `json:"id,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+type wsClient struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+	// the login the connection was opened with
+	sessionID uuid.UUID
+	send      chan wsServerMessage
+	// closing carries the code the connection is being closed with; only the first counts
+	closing chan websocket.CloseError
+	done    chan struct{}
+
+	mu       sync.Mutex
+	channels map[string]*stream.Subscription
+	expiry   *time.Timer
+}
+
+func (cfg *apiConfig) websocketHandler(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		// browsers cannot set headers on a WebSocket handshake
+		token = r.URL.Query().Get("access_token")
+	}
+	session, err := auth.ValidateSessionJWT(token, cfg.secret)
+	// a connection has to end when its token does, and with its login
+	if err != nil || session.ExpiresAt.IsZero() || session.ID == uuid.Nil {
+		w.WriteHeader(401)
+		return
+	}
+	userID := session.UserID
+	// subscribed before checking, so a sign out can't slip in between
+	signedOut := cfg.broker.Subscribe([]string{sessionTopic(session.ID), accountTopic(userID)})
+	defer signedOut.Close()
+	dbUser, err := cfg.dbQueries.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "no rows in result set") {
+			w.WriteHeader(401)
+			return
+		}
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if msg := lockedOut(dbUser); msg != "" {
+		respondWithText(w, 403, msg)
+		return
+	}
+	active, err := cfg.dbQueries.SessionActive(r.Context(), session.ID)
+	if err != nil {
+		fmt.Println(err.Error())
+		w.WriteHeader(500)
+		return
+	}
+	if !active {
+		w.WriteHeader(401)
+		return
+	}
+	conn, err := wsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	client := &wsClient{
+		cfg:       cfg,
+		conn:      conn,
+		userID:    userID,
+		sessionID: session.ID,
+		send:      make(chan wsServerMessage, wsSendBuffer),
+		closing:   make(chan websocket.CloseError, 1),
+		done:      make(chan struct{}),
+		channels:  map[string]*stream.Subscription{},
+	}
+	client.expiry = time.AfterFunc(time.Until(session.ExpiresAt), func() {
+		client.close(wsCloseExpired, "token expired")
+	})
+	go func() {
+		if _, ok := <-signedOut.Events; ok {
+			client.close(wsCloseRevoked, "session revoked")
+		}
+	}()
+	go client.readLoop(r.Context())
+	client.writeLoop()
+
+	close(client.done)
+	client.expiry.Stop()
+	client.mu.Lock()
+	for _, sub := range client.channels {
+		sub.Close()
+	}
+	client.mu.Unlock()
+	conn.Close()
+}
+
+func (c *wsClient) close(code int, text string) {
+	select {
+	case c.closing <- websocket.CloseError{Code: code, Text: text}:
+	default:
+	}
+}
+
+// writeLoop is the only writer on the connection, as gorilla/websocket requires.
+func (c *wsClient) writeLoop() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if c.conn.WriteJSON(msg) != nil {
+				return
+			}
+		case <-ping.C:
+			err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait))
+			if err != nil {
+				return
+			}
+		case closeErr := <-c.closing:
+			c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeErr.Code, closeErr.Text), time.Now().Add(wsWriteWait))
+			return
+		}
+	}
+}
+
+func (c *wsClient) readLoop(ctx context.Context) {
+	c.conn.SetReadLimit(wsMaxMessage)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		msg := wsClientMessage{}
+		err := c.conn.ReadJSON(&msg)
+		if err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				c.reply(wsServerMessage{Type: "error", Message: "Invalid message"})
+				continue
+			}
+			// the peer went away or sent a close frame; the reply echoes its code
+			c.close(websocket.CloseNormalClosure, "")
+			return
+		}
+		switch msg.Type {
+		case "subscribe":
+			c.subscribe(ctx, msg.Channel, msg.LastEventID)
+		case "unsubscribe":
+			c.unsubscribe(msg.Channel)
+		case "auth":
+			c.reauthenticate(msg.Token)
+		default:
+			c.reply(wsServerMessage{Type: "error", Message: "Unknown message type"})
+		}
+	}
+}
+
+// reply queues a message for the client, waiting for room if necessary.
+func (c *wsClient) reply(msg wsServerMessage) {
+	select {
+	case c.send <- msg:
+	case <-c.done:
+	}
+}
+
+func (c *wsClient) reauthenticate(token string) {
+	// a token from another login would outlive the one the connection ends with
+	session, err := auth.ValidateSessionJWT(token, c.cfg.secret)
+	if err != nil || session.UserID != c.userID || session.ID != c.sessionID || session.ExpiresAt.IsZero() {
+		c.reply(wsServerMessage{Type: "error", Message: "Invalid token"})
+		return
+	}
+	c.expiry.Reset(time.Until(session.ExpiresAt))
+	c.reply(wsServerMessage{Type: "authenticated"})
+}
+
+func (c *wsClient) channelTopics(ctx context.Context, channel string) ([]string, error) {
+	switch {
+	case channel == "timeline":
+		return c.cfg.timelineTopics(ctx, c.userID)
+	case channel == "notifications":
+		return []string{notificationsTopic(c.userID)}, nil
+	case channel == "messages":
+		return []string{messagesTopic(c.userID)}, nil
+	case strings.HasPrefix(channel, wsThreadPrefix):
+		chirpID, err := uuid.Parse(strings.TrimPrefix(channel, wsThreadPrefix))
+		if err != nil {
+			return nil, &requestError{404, "Chirp not found"}
+		}
+		dbChirp, err := c.cfg.dbQueries.GetChirp(ctx, chirpID)
+		if err != nil {
+			if strings.Contains(err.Error(), "no rows in result set") {
+				return nil, &requestError{404, "Chirp not found"}
+			}
+			return nil, err
+		}
+		visible, err := canView(ctx, c.cfg.dbQueries, dbChirp, c.userID)
+		if err != nil {
+			return nil, err
+		}
+		if !visible {
+			return nil, &requestError{404, "Chirp not found"}
+		}
+		return []string{conversationTopic(conversationID(dbChirp))}, nil
+	}
+	return nil, &requestError{400, "Unknown channel"}
+}
+
+func (c *wsClient) subscribe(ctx context.Context, channel string, lastEventID int64) {
+	c.mu.Lock()
+	_, exists := c.channels[channel]
+	count := len(c.channels)
+	c.mu.Unlock()
+	if exists {
+		c.reply(wsServerMessage{Type: "subscribed", Channel: channel})
+		return
+	}
+	if count >= wsMaxChannels {
+		c.reply(wsServerMessage{Type: "error", Channel: channel, Message: "Too many subscriptions"})
+		return
+	}
+	topics, err := c.channelTopics(ctx, channel)
+	if err != nil {
+		msg := "Internal error"
+		if reqErr, ok := err.(*requestError); ok {
+			msg = reqErr.msg
+		} else {
+			fmt.Printf("Error subscribing to %s: %v\n", channel, err)
+		}
+		c.reply(wsServerMessage{Type: "error", Channel: channel, Message: msg})
+		return
+	}
+	// blocks made after subscribing take effect on the next subscription
+	hidden, err := c.cfg.blockedTopics(ctx, c.userID)
+	if err != nil {
+		fmt.Printf("Error subscribing to %s: %v\n", channel, err)
+		c.reply(wsServerMessage{Type: "error", Channel: channel, Message: "Internal error"})
+		return
+	}
+	sub := c.cfg.broker.Subscribe(topics)
+	c.mu.Lock()
+	c.channels[channel] = sub
+	c.mu.Unlock()
+	c.reply(wsServerMessage{Type: "subscribed", Channel: channel})
+	if lastEventID > 0 {
+		lastEventID = c.replay(ctx, channel, topics, hidden, lastEventID)
+	}
+	go c.forward(channel, sub, hidden, lastEventID)
+}
+
+// replay sends the events a resubscribing client missed, returning the last
+// one sent so the live subscription can skip duplicates.
+func (c *wsClient) replay(ctx context.Context, channel string, topics []string, hidden map[string]bool, lastEventID int64) int64 {
+	dbEvents, err := c.cfg.dbQueries.GetTopicStreamEventsAfter(ctx, database.GetTopicStreamEventsAfterParams{AfterID: lastEventID, Topics: topics, RowLimit: streamReplayLimit})
+	if err != nil {
+		fmt.Printf("Error replaying stream events: %v\n", err)
+		return lastEventID
+	}
+	if len(dbEvents) == streamReplayLimit {
+		c.reply(wsServerMessage{Type: "reset", Channel: channel})
+		return dbEvents[len(dbEvents)-1].ID
+	}
+	events, err := c.cfg.loadStreamEvents(ctx, dbEvents)
+	if err != nil {
+		fmt.Printf("Error replaying stream events: %v\n", err)
+		return lastEventID
+	}
+	for _, event := range events {
+		if hiddenEvent(event, hidden) {
+			continue
+		}
+		c.reply(wsServerMessage{Type: "event", Channel: channel, ID: event.ID, Event: event.Type, Data: event.Data})
+	}
+	if len(dbEvents) > 0 {
+		return dbEvents[len(dbEvents)-1].ID
+	}
+	return lastEventID
+}
+
+func (c *wsClient) forward(channel string, sub *stream.Subscription, hidden map[string]bool, lastEventID int64) {
+	for event := range sub.Events {
+		if event.ID <= lastEventID || hiddenEvent(event, hidden) {
+			continue
+		}
+		// live events never wait on a client that has stopped reading
+		select {
+		case c.send <- wsServerMessage{Type: "event", Channel: channel, ID: event.ID, Event: event.Type, Data: event.Data}:
+		default:
+			c.close(wsCloseTooSlow, "client too slow")
+			return
+		}
+	}
+	if sub.Dropped() {
+		c.mu.Lock()
+		delete(c.channels, channel)
+		c.mu.Unlock()
+		c.reply(wsServerMessage{Type: "unsubscribed", Channel: channel, Message: "Subscription fell behind; resubscribe with last_event_id"})
+	}
+}
+
+func (c *wsClient) unsubscribe(channel string) {
+	c.mu.Lock()
+	sub, ok := c.channels[channel]
+	delete(c.channels, channel)
+	c.mu.Unlock()
+	if ok {
+		sub.Close()
+	}
+	c.reply(wsServerMessage{Type: "unsubscribed", Channel: channel})
+}